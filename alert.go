@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"time"
+)
+
+// Represents a traffic alert
+type Alert struct {
+	Name        string     `json:"name"`  // Name of the rule which generated the alert
+	Scope       string     `json:"scope"` // Scope of the rule: empty for the whole log, SectionScope, HostScope or TerminationScope
+	Key         string     `json:"key"`   // Section, host or termination state which generated the alert when the rule is scoped
+	State       AlertState `json:"state"`
+	Hits        int64      `json:"hits"`
+	AverageRate int64      `json:"average_rate"`
+	Threshold   int64      `json:"threshold"`    // Threshold of the rule in hits/s, in hits for the termination state alert or in seconds for the no data alert
+	ActiveAt    time.Time  `json:"active_at"`    // Time the rule condition started to be met
+	StartsAt    time.Time  `json:"starts_at"`    // Time the alert started to fire, kept while the alert is ongoing
+	TriggeredAt time.Time  `json:"triggered_at"` // Time of the last check which updated the alert
+
+	// Value and ValueThreshold are the measure of the rules which are not based on the rate of hits,
+	// such as the burn rate of an error budget or the apdex score
+	Value          float64 `json:"value,omitempty"`
+	ValueThreshold float64 `json:"value_threshold,omitempty"`
+}
+
+// AlertState is the state of an alert in its lifecycle
+type AlertState string
+
+const (
+	AlertPending  AlertState = "pending"  // The rule condition is met but not for long enough to fire
+	AlertFiring   AlertState = "firing"   // The rule condition is met
+	AlertResolved AlertState = "resolved" // The rule condition came back to normal
+)
+
+// Names of the alerts generated by the monitor
+const (
+	HighTrafficAlert      = "high_traffic"
+	LowTrafficAlert       = "low_traffic"
+	NoDataAlert           = "no_data"
+	SLOFastBurnAlert      = "slo_fast_burn"
+	SLOSlowBurnAlert      = "slo_slow_burn"
+	LowApdexAlert         = "low_apdex"
+	TerminationStateAlert = "termination_state"
+)
+
+// Severity of the alerts by rule name
+var alertSeverities = map[string]string{
+	HighTrafficAlert:      "warning",
+	LowTrafficAlert:       "warning",
+	NoDataAlert:           "critical",
+	SLOFastBurnAlert:      "critical",
+	SLOSlowBurnAlert:      "warning",
+	LowApdexAlert:         "warning",
+	TerminationStateAlert: "warning",
+}
+
+// Scopes of the alerting rules
+const (
+	SectionScope     = "section"
+	HostScope        = "host"
+	TerminationScope = "termination_state"
+
+	// AnyKey is the key of a threshold applying to every section or host of the scope
+	AnyKey = "*"
+)
+
+// Status returns whether the alert is pending, firing or resolved
+func (a Alert) Status() string {
+	return string(a.State)
+}
+
+// ID describes the rule and the key which generated the alert in a readable way
+func (a Alert) ID() string {
+	return a.Name + "|" + a.Scope + "|" + a.Key
+}
+
+// Fingerprint identifies the alert by hashing its labels,
+// it is stable for all the checks and transitions of an ongoing alert
+func (a Alert) Fingerprint() string {
+	labels := a.Labels()
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	hash := fnv.New64a()
+	for _, name := range names {
+		hash.Write([]byte(name))
+		hash.Write([]byte{0xff})
+		hash.Write([]byte(labels[name]))
+		hash.Write([]byte{0xff})
+	}
+
+	return fmt.Sprintf("%016x", hash.Sum64())
+}
+
+// Subject describes what generated the alert
+func (a Alert) Subject() string {
+	switch a.Scope {
+	case SectionScope:
+		return fmt.Sprintf("section /%s", a.Key)
+	case HostScope:
+		return fmt.Sprintf("client %s", a.Key)
+	case TerminationScope:
+		return fmt.Sprintf("termination state %s", a.Key)
+	default:
+		return "traffic"
+	}
+}
+
+// Severity returns the severity of the rule which generated the alert
+func (a Alert) Severity() string {
+	if severity, ok := alertSeverities[a.Name]; ok {
+		return severity
+	}
+	return "warning"
+}
+
+// Labels identifies the alert: its rule name, its severity and the section or host when the rule is scoped
+func (a Alert) Labels() map[string]string {
+	labels := map[string]string{
+		"alertname": a.Name,
+		"severity":  a.Severity(),
+	}
+	if len(a.Scope) > 0 {
+		labels[a.Scope] = a.Key
+	}
+
+	return labels
+}
+
+// Description describes the values which generated the alert
+func (a Alert) Description() string {
+	switch a.Name {
+	case NoDataAlert:
+		return fmt.Sprintf("no line received for at least %vs", a.Threshold)
+	case SLOFastBurnAlert, SLOSlowBurnAlert:
+		return fmt.Sprintf("%s - hits: %v - error budget burn rate: %.2f - threshold: %v", a.Subject(), a.Hits, a.Value, a.ValueThreshold)
+	case LowApdexAlert:
+		return fmt.Sprintf("%s - hits: %v - apdex: %.2f - threshold: %v", a.Subject(), a.Hits, a.Value, a.ValueThreshold)
+	case TerminationStateAlert:
+		return fmt.Sprintf("%s - sessions: %v - threshold: %v sessions", a.Subject(), a.Hits, a.Threshold)
+	}
+	return fmt.Sprintf("%s - hits: %v - rate: %v hits/s - threshold: %v hits/s", a.Subject(), a.Hits, a.AverageRate, a.Threshold)
+}
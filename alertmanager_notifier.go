@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Default values of the alertmanager notifier
+const (
+	defaultAlertmanagerResendInterval = time.Minute
+	defaultAlertmanagerTimeout        = 10 * time.Second
+	defaultAlertmanagerQueueSize      = 100
+
+	alertmanagerAlertsPath = "/api/v2/alerts"
+)
+
+// AlertmanagerConfig describes how to push the alerts to Prometheus Alertmanager,
+// zero values are replaced by the default ones
+type AlertmanagerConfig struct {
+	URL            string        // Base url of the alertmanager
+	GeneratorURL   string        // Link to the monitor added to the alerts
+	ResendInterval time.Duration // Interval to send again the firing alerts
+	Timeout        time.Duration // Timeout of a push
+	QueueSize      int           // Number of alerts waiting to be pushed
+}
+
+// AlertmanagerNotifier pushes the alerts to the Alertmanager v2 API.
+// As Prometheus does, the firing alerts are sent again periodically until they are resolved,
+// so the alertmanager never considers them as resolved by timeout while they are still firing.
+type AlertmanagerNotifier struct {
+	config AlertmanagerConfig
+	client *http.Client
+
+	queue chan Alert
+	wg    sync.WaitGroup
+
+	// alerts to push by fingerprint, resolved alerts are removed once pushed
+	alerts map[string]alertmanagerAlert
+}
+
+// alertmanagerAlert is an alert in the format of the Alertmanager v2 API
+type alertmanagerAlert struct {
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations"`
+	StartsAt     time.Time         `json:"startsAt"`
+	EndsAt       time.Time         `json:"endsAt"`
+	GeneratorURL string            `json:"generatorURL,omitempty"`
+
+	resolved bool
+}
+
+func NewAlertmanagerNotifier(config AlertmanagerConfig) (*AlertmanagerNotifier, error) {
+	if len(config.URL) == 0 {
+		return nil, errors.New("alertmanager url is missing")
+	}
+	if config.ResendInterval == 0 {
+		config.ResendInterval = defaultAlertmanagerResendInterval
+	}
+	if config.Timeout == 0 {
+		config.Timeout = defaultAlertmanagerTimeout
+	}
+	if config.QueueSize == 0 {
+		config.QueueSize = defaultAlertmanagerQueueSize
+	}
+
+	n := &AlertmanagerNotifier{
+		config: config,
+		client: &http.Client{Timeout: config.Timeout},
+		queue:  make(chan Alert, config.QueueSize),
+		alerts: make(map[string]alertmanagerAlert),
+	}
+
+	n.wg.Add(1)
+	go n.run()
+
+	return n, nil
+}
+
+// Notify queues the alert, it returns ErrQueueFull when the alert cannot be queued
+func (n *AlertmanagerNotifier) Notify(alert Alert) error {
+	select {
+	case n.queue <- alert:
+		return nil
+	default:
+		return fmt.Errorf("alertmanager: %w - alert %s dropped", ErrQueueFull, alert.ID())
+	}
+}
+
+// Close stops the notifier once the queued alerts are pushed
+func (n *AlertmanagerNotifier) Close() error {
+	close(n.queue)
+	n.wg.Wait()
+	return nil
+}
+
+// run pushes the queued alerts and sends again the firing ones periodically
+func (n *AlertmanagerNotifier) run() {
+	defer n.wg.Done()
+
+	ticker := time.NewTicker(n.config.ResendInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case alert, ok := <-n.queue:
+			if !ok {
+				return
+			}
+			n.update(alert)
+		case <-ticker.C:
+		}
+
+		err := n.push()
+		if err != nil {
+			log.Println("cannot push alerts to alertmanager", "err", err)
+		}
+	}
+}
+
+// update records the alert transition
+func (n *AlertmanagerNotifier) update(alert Alert) {
+	startsAt := alert.StartsAt
+	if startsAt.IsZero() {
+		// the alert never fired
+		startsAt = alert.TriggeredAt
+	}
+
+	// the end of the firing alerts is extended at each push
+	var endsAt time.Time
+	if alert.State == AlertResolved {
+		endsAt = alert.TriggeredAt
+	}
+
+	n.alerts[alert.Fingerprint()] = alertmanagerAlert{
+		Labels: alert.Labels(),
+		Annotations: map[string]string{
+			"summary":     fmt.Sprintf("%s on %s", strings.Replace(alert.Name, "_", " ", -1), alert.Subject()),
+			"description": alert.Description(),
+		},
+		StartsAt:     startsAt,
+		EndsAt:       endsAt,
+		GeneratorURL: n.config.GeneratorURL,
+		resolved:     alert.State == AlertResolved,
+	}
+}
+
+// push sends all the alerts to the alertmanager
+func (n *AlertmanagerNotifier) push() error {
+	if len(n.alerts) == 0 {
+		return nil
+	}
+
+	alerts := make([]alertmanagerAlert, 0, len(n.alerts))
+	for fingerprint, alert := range n.alerts {
+		if !alert.resolved {
+			// the alertmanager resolves the firing alerts which are not sent again before they end
+			alert.EndsAt = time.Now().Add(4 * n.config.ResendInterval)
+			n.alerts[fingerprint] = alert
+		}
+		alerts = append(alerts, alert)
+	}
+
+	payload, err := json.Marshal(alerts)
+	if err != nil {
+		return fmt.Errorf("cannot encode alerts: %w", err)
+	}
+
+	resp, err := n.client.Post(strings.TrimSuffix(n.config.URL, "/")+alertmanagerAlertsPath, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err := resp.Body.Close()
+		if err != nil {
+			log.Println("failed to close alertmanager response", "err", err)
+		}
+	}()
+
+	if !IsSuccess(resp.StatusCode) {
+		// resolved alerts are kept to be sent again with the next push
+		return fmt.Errorf("unexpected alertmanager response status: %s", resp.Status)
+	}
+
+	for fingerprint, alert := range n.alerts {
+		if alert.resolved {
+			delete(n.alerts, fingerprint)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeAlertmanager records the alerts pushed to the v2 API
+type fakeAlertmanager struct {
+	sync.Mutex
+	status int
+	pushes [][]alertmanagerAlert
+}
+
+func (f *fakeAlertmanager) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+
+	if r.Method != http.MethodPost || r.URL.Path != "/api/v2/alerts" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	var alerts []alertmanagerAlert
+	err := json.NewDecoder(r.Body).Decode(&alerts)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	f.pushes = append(f.pushes, alerts)
+	w.WriteHeader(f.status)
+}
+
+// waitPushes waits until the alertmanager received a number of pushes
+func (f *fakeAlertmanager) waitPushes(t *testing.T, count int) [][]alertmanagerAlert {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		f.Lock()
+		if len(f.pushes) >= count {
+			pushes := f.pushes
+			f.Unlock()
+			return pushes
+		}
+		f.Unlock()
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	t.Fatal("alerts not pushed", "expected", count)
+	return nil
+}
+
+// setStatus changes the response status and returns the number of pushes received
+func (f *fakeAlertmanager) setStatus(status int) int {
+	f.Lock()
+	defer f.Unlock()
+	f.status = status
+	return len(f.pushes)
+}
+
+func TestAlertmanagerNotifier_Notify(t *testing.T) {
+	startsAt := time.Date(2006, 01, 02, 15, 04, 05, 000, time.UTC)
+	firing := Alert{
+		Name:        HighTrafficAlert,
+		Scope:       SectionScope,
+		Key:         "login",
+		State:       AlertFiring,
+		Hits:        6000,
+		AverageRate: 100,
+		Threshold:   50,
+		StartsAt:    startsAt,
+		TriggeredAt: startsAt,
+	}
+	// the ongoing alert updated with more hits
+	increased := firing
+	increased.Hits = 12000
+	increased.AverageRate = 200
+	increased.TriggeredAt = startsAt.Add(time.Minute)
+
+	resolved := firing
+	resolved.State = AlertResolved
+	resolved.Hits = 60
+	resolved.AverageRate = 1
+	resolved.TriggeredAt = startsAt.Add(2 * time.Minute)
+
+	am := &fakeAlertmanager{status: http.StatusOK}
+	server := httptest.NewServer(am)
+	defer server.Close()
+
+	n, err := NewAlertmanagerNotifier(AlertmanagerConfig{
+		URL:            server.URL,
+		GeneratorURL:   "http://monitor:8080",
+		ResendInterval: 50 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// firing alert pushed with its labels and annotations
+	err = n.Notify(firing)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pushes := am.waitPushes(t, 1)
+	expectedLabels := map[string]string{
+		"alertname": "high_traffic",
+		"severity":  "warning",
+		"section":   "login",
+	}
+	pushed := pushes[0][0]
+	if !reflect.DeepEqual(expectedLabels, pushed.Labels) {
+		t.Fatal("unexpected labels", "expected", expectedLabels, "actual", pushed.Labels)
+	}
+	if pushed.Annotations["summary"] != "high traffic on section /login" {
+		t.Fatal("unexpected summary", "actual", pushed.Annotations["summary"])
+	}
+	if !pushed.StartsAt.Equal(startsAt) || !pushed.EndsAt.After(time.Now()) {
+		t.Fatal("unexpected firing period", "startsAt", pushed.StartsAt, "endsAt", pushed.EndsAt)
+	}
+	if pushed.GeneratorURL != "http://monitor:8080" {
+		t.Fatal("unexpected generator url", "actual", pushed.GeneratorURL)
+	}
+
+	// firing alert sent again periodically
+	pushes = am.waitPushes(t, 2)
+	if len(pushes[1]) != 1 || !reflect.DeepEqual(expectedLabels, pushes[1][0].Labels) {
+		t.Fatal("firing alert not sent again", "actual", pushes[1])
+	}
+
+	// the ongoing alert keeps its start
+	err = n.Notify(increased)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = n.Notify(resolved)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = n.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	am.Lock()
+	last := am.pushes[len(am.pushes)-1][0]
+	am.Unlock()
+	if !last.StartsAt.Equal(startsAt) || !last.EndsAt.Equal(resolved.TriggeredAt) {
+		t.Fatal("unexpected resolved period", "startsAt", last.StartsAt, "endsAt", last.EndsAt)
+	}
+}
+
+func TestAlertmanagerNotifier_ResolvedSentAgainOnFailure(t *testing.T) {
+	am := &fakeAlertmanager{status: http.StatusServiceUnavailable}
+	server := httptest.NewServer(am)
+	defer server.Close()
+
+	n, err := NewAlertmanagerNotifier(AlertmanagerConfig{URL: server.URL, ResendInterval: 20 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer n.Close()
+
+	err = n.Notify(Alert{Name: NoDataAlert, State: AlertResolved, Threshold: 30, TriggeredAt: time.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	am.waitPushes(t, 1)
+	rejected := am.setStatus(http.StatusOK)
+	count := len(am.waitPushes(t, rejected+1))
+
+	// once accepted the resolved alert is not sent anymore
+	time.Sleep(100 * time.Millisecond)
+	am.Lock()
+	defer am.Unlock()
+	if len(am.pushes) != count {
+		t.Fatal("resolved alert sent after being accepted", "expected", count, "actual", len(am.pushes))
+	}
+}
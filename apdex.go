@@ -0,0 +1,100 @@
+package main
+
+import (
+	"sort"
+	"time"
+
+	"github.com/ali.ghanem/http-log-monitoring/commonlog"
+	"github.com/ali.ghanem/http-log-monitoring/metric"
+)
+
+// Apdex classifies the requests of the sections by their duration against a target T:
+// satisfied under T, tolerating under 4T and frustrated above
+type Apdex struct {
+	// Targets by section, the target of the key "*" applies to the sections without their own target
+	Targets map[string]time.Duration
+
+	// Satisfied, Tolerating and Total store the number of requests by section and bucket of time
+	Satisfied  *metric.TimeSeriesVec
+	Tolerating *metric.TimeSeriesVec
+	Total      *metric.TimeSeriesVec
+}
+
+// ApdexScore is the apdex of a section: (satisfied + tolerating / 2) / total
+type ApdexScore struct {
+	Section    string        `json:"section"`
+	Target     time.Duration `json:"target"`
+	Satisfied  int64         `json:"satisfied"`
+	Tolerating int64         `json:"tolerating"`
+	Total      int64         `json:"total"`
+	Score      float64       `json:"score"`
+}
+
+func NewApdex(targets map[string]time.Duration, clock metric.Clock) *Apdex {
+	return &Apdex{
+		Targets:    targets,
+		Satisfied:  metric.NewTimeSeriesVecWithClock(clock),
+		Tolerating: metric.NewTimeSeriesVecWithClock(clock),
+		Total:      metric.NewTimeSeriesVecWithClock(clock),
+	}
+}
+
+// target returns the target of the section or the default one
+func (a *Apdex) target(section string) (time.Duration, bool) {
+	if target, ok := a.Targets[section]; ok {
+		return target, true
+	}
+
+	target, ok := a.Targets[AnyKey]
+	return target, ok
+}
+
+// Record classifies the event weight times when its duration is logged and its section has a target
+func (a *Apdex) Record(event commonlog.Event, weight int64) {
+	if !event.HasDuration {
+		return
+	}
+	target, ok := a.target(event.Section)
+	if !ok {
+		return
+	}
+
+	a.Total.Inc(event.Section, event.Date, weight)
+	switch {
+	case event.Duration <= target:
+		a.Satisfied.Inc(event.Section, event.Date, weight)
+	case event.Duration <= 4*target:
+		a.Tolerating.Inc(event.Section, event.Date, weight)
+	}
+}
+
+// Scores returns the apdex of the sections which received requests since a date, sorted by section
+func (a *Apdex) Scores(since time.Time) []ApdexScore {
+	var scores []ApdexScore
+	for section, total := range a.Total.AllCountsSince(since) {
+		if total == 0 {
+			continue
+		}
+
+		target, _ := a.target(section)
+		score := ApdexScore{
+			Section:    section,
+			Target:     target,
+			Satisfied:  a.Satisfied.CountSince(section, since),
+			Tolerating: a.Tolerating.CountSince(section, since),
+			Total:      total,
+		}
+		score.Score = (float64(score.Satisfied) + float64(score.Tolerating)/2) / float64(total)
+		scores = append(scores, score)
+	}
+
+	sort.Slice(scores, func(i, j int) bool {
+		return scores[i].Section < scores[j].Section
+	})
+	return scores
+}
+
+// Clean cleans the requests older than a date
+func (a *Apdex) Clean(before time.Time) int64 {
+	return a.Satisfied.Clean(before) + a.Tolerating.Clean(before) + a.Total.Clean(before)
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ali.ghanem/http-log-monitoring/commonlog"
+	"github.com/ali.ghanem/http-log-monitoring/metric"
+	"github.com/ali.ghanem/http-log-monitoring/timetest"
+)
+
+// timedEvent returns a request of the section served in a duration
+func timedEvent(section string, date time.Time, duration time.Duration) commonlog.Event {
+	return commonlog.Event{Date: date, Section: section, Duration: duration, HasDuration: true}
+}
+
+func TestApdex_Scores(t *testing.T) {
+	timetest.FreezeTime()
+	defer timetest.UnfreezeTime()
+
+	date := time.Now().Add(-10 * time.Second)
+	apdex := NewApdex(map[string]time.Duration{"api": 100 * time.Millisecond, AnyKey: time.Second}, metric.SystemClock)
+
+	for _, event := range []commonlog.Event{
+		timedEvent("api", date, 50*time.Millisecond),
+		timedEvent("api", date, 100*time.Millisecond),
+		timedEvent("api", date, 300*time.Millisecond),
+		timedEvent("api", date, 2*time.Second),
+		timedEvent("pages", date, 500*time.Millisecond),
+		// requests out of the interval
+		timedEvent("pages", date.Add(-2*time.Minute), 10*time.Second),
+		// duration not logged
+		{Date: date, Section: "api"},
+	} {
+		apdex.Record(event, 1)
+	}
+
+	expected := []ApdexScore{
+		{Section: "api", Target: 100 * time.Millisecond, Satisfied: 2, Tolerating: 1, Total: 4, Score: 0.625},
+		{Section: "pages", Target: time.Second, Satisfied: 1, Total: 1, Score: 1},
+	}
+	actual := apdex.Scores(time.Now().Add(-1 * time.Minute))
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatal("unexpected scores", "expected", expected, "actual", actual)
+	}
+}
+
+func TestLogMonitor_CheckApdex(t *testing.T) {
+	timetest.FreezeTime()
+	defer timetest.UnfreezeTime()
+
+	triggeredAt := time.Date(2006, 01, 02, 15, 04, 05, 000, time.UTC)
+	date := triggeredAt.Add(-10 * time.Second)
+
+	m := setupLogMonitor(t)
+	m.Apdex = NewApdex(map[string]time.Duration{AnyKey: 100 * time.Millisecond}, metric.SystemClock)
+	for i := 0; i < 60; i++ {
+		m.HandleEvent(timedEvent("api", date, time.Second))
+		m.HandleEvent(timedEvent("pages", date, 10*time.Millisecond))
+	}
+
+	thresholds := map[string]float64{"api": 0.7, "pages": 0.7}
+	expected := []*Alert{
+		{
+			Name:           LowApdexAlert,
+			Scope:          SectionScope,
+			Key:            "api",
+			State:          AlertFiring,
+			Hits:           60,
+			AverageRate:    1,
+			ActiveAt:       triggeredAt,
+			StartsAt:       triggeredAt,
+			TriggeredAt:    triggeredAt,
+			Value:          0,
+			ValueThreshold: 0.7,
+		},
+	}
+	alerts := m.CheckApdex(time.Minute, thresholds)
+	if !reflect.DeepEqual(expected, alerts) {
+		t.Fatal("unexpected alerts", "expected", expected, "actual", alerts)
+	}
+
+	// no more request on the section
+	m.Apdex.Clean(time.Now())
+	alerts = m.CheckApdex(time.Minute, thresholds)
+	if len(alerts) != 1 || alerts[0].State != AlertResolved || alerts[0].Key != "api" {
+		t.Fatal("unexpected alerts", "expected", AlertResolved, "actual", alerts)
+	}
+	if len(m.LastApdexAlerts) != 0 {
+		t.Fatal("unexpected alerts saved", "actual", m.LastApdexAlerts)
+	}
+}
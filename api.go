@@ -0,0 +1,247 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// API exposes the management endpoints of the monitor
+type API struct {
+	Tokens Tokens // Bearer tokens accepted by the management endpoints
+
+	Silencer *Silencer
+	Journal  *Journal // optional, the alerts history is not available without journal
+
+	Monitor          *LogMonitor
+	TopSectionsCount int // Number of sections with maximum hits in the statistics
+
+	Ingest http.Handler // optional, endpoint receiving the logs pushed by http
+}
+
+// Tokens are the bearer tokens accepted by an endpoint
+type Tokens []string
+
+// String redacts the tokens, so the configuration can be logged
+func (t Tokens) String() string {
+	redactedTokens := make([]string, len(t))
+	for i := range redactedTokens {
+		redactedTokens[i] = redacted
+	}
+	return fmt.Sprint(redactedTokens)
+}
+
+// authorizedBearer tells if the request has one of the tokens, sent with the bearer scheme
+func authorizedBearer(r *http.Request, tokens Tokens) bool {
+	header := r.Header.Get("Authorization")
+	if !strings.HasPrefix(header, "Bearer ") {
+		return false
+	}
+
+	token := strings.TrimPrefix(header, "Bearer ")
+	for _, accepted := range tokens {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(accepted)) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// Handler routes the requests to the endpoints
+func (a *API) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/silences", a.authenticated(a.handleSilences))
+	mux.HandleFunc("/api/silences/", a.authenticated(a.handleSilence))
+	mux.HandleFunc("/api/maintenance-windows", a.authenticated(a.handleMaintenanceWindows))
+	mux.HandleFunc("/api/alerts/history", a.authenticated(a.handleAlertsHistory))
+	mux.HandleFunc("/api/alerts/time-in-alert", a.authenticated(a.handleTimeInAlert))
+	mux.HandleFunc("/api/statistics", a.authenticated(a.handleStatistics))
+	// the ingest endpoint is authenticated by its own tokens
+	if a.Ingest != nil {
+		mux.Handle("/api/ingest", a.Ingest)
+	}
+
+	return mux
+}
+
+// authenticated rejects the requests without one of the tokens of the api
+func (a *API) authenticated(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !authorizedBearer(r, a.Tokens) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, "invalid token")
+			return
+		}
+
+		handler(w, r)
+	}
+}
+
+// handleSilences lists or creates the silences
+func (a *API) handleSilences(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, a.Silencer.Silences())
+	case http.MethodPost:
+		var silence Silence
+		err := json.NewDecoder(r.Body).Decode(&silence)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid silence: "+err.Error())
+			return
+		}
+
+		// the id is generated by the silencer
+		silence.ID = ""
+		silence, err = a.Silencer.Add(silence)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		log.Println("silence created", "id", silence.ID, "matchers", silence.Matchers, "ends at", silence.EndsAt)
+		writeJSON(w, http.StatusCreated, silence)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// handleSilence deletes a silence identified by its id in the path
+func (a *API) handleSilence(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	id := strings.TrimPrefix(r.URL.Path, "/api/silences/")
+	if !a.Silencer.Delete(id) {
+		writeError(w, http.StatusNotFound, "silence not found")
+		return
+	}
+
+	log.Println("silence deleted", "id", id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleMaintenanceWindows lists the maintenance windows
+func (a *API) handleMaintenanceWindows(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, a.Silencer.MaintenanceWindows())
+}
+
+// handleAlertsHistory lists the alert transitions recorded in the journal matching the query filters
+func (a *API) handleAlertsHistory(w http.ResponseWriter, r *http.Request) {
+	alerts, ok := a.journalAlerts(w, r)
+	if !ok {
+		return
+	}
+
+	if alerts == nil {
+		alerts = []Alert{}
+	}
+	writeJSON(w, http.StatusOK, alerts)
+}
+
+// handleTimeInAlert computes the time spent firing by the alerts recorded in the journal matching the query filters
+func (a *API) handleTimeInAlert(w http.ResponseWriter, r *http.Request) {
+	alerts, ok := a.journalAlerts(w, r)
+	if !ok {
+		return
+	}
+
+	until := time.Now()
+	if raw := r.URL.Query().Get("until"); len(raw) > 0 {
+		// already validated by journalAlerts
+		until, _ = time.Parse(time.RFC3339, raw)
+	}
+
+	writeJSON(w, http.StatusOK, TimeInAlert(alerts, until))
+}
+
+// handleStatistics returns the statistics of the traffic, filtered by log source with the query parameter source
+func (a *API) handleStatistics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	source := r.URL.Query().Get("source")
+	if len(source) == 0 {
+		writeJSON(w, http.StatusOK, a.Monitor.Statistics(a.TopSectionsCount))
+		return
+	}
+
+	statistics, ok := a.Monitor.SourceStatistics(source, a.TopSectionsCount)
+	if !ok {
+		writeError(w, http.StatusNotFound, "source not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, statistics)
+}
+
+// journalAlerts reads the alerts of the journal matching the query filters: name, scope, key, state, since and until.
+// It writes the error response and returns false when the alerts cannot be read.
+func (a *API) journalAlerts(w http.ResponseWriter, r *http.Request) ([]Alert, bool) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return nil, false
+	}
+
+	if a.Journal == nil {
+		writeError(w, http.StatusNotFound, "alert journal is not enabled")
+		return nil, false
+	}
+
+	query := r.URL.Query()
+	filter := JournalFilter{
+		Name:  query.Get("name"),
+		Scope: query.Get("scope"),
+		Key:   query.Get("key"),
+		State: AlertState(query.Get("state")),
+	}
+
+	for param, date := range map[string]*time.Time{"since": &filter.Since, "until": &filter.Until} {
+		raw := query.Get(param)
+		if len(raw) == 0 {
+			continue
+		}
+
+		var err error
+		*date, err = time.Parse(time.RFC3339, raw)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid "+param+": "+err.Error())
+			return nil, false
+		}
+	}
+
+	alerts, err := a.Journal.Alerts(filter)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return nil, false
+	}
+
+	return alerts, true
+}
+
+// writeJSON writes the value in JSON with the status code
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	err := json.NewEncoder(w).Encode(value)
+	if err != nil {
+		log.Println("cannot write response", "err", err)
+	}
+}
+
+// writeError writes an error message in JSON with the status code
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
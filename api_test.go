@@ -0,0 +1,219 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ali.ghanem/http-log-monitoring/commonlog"
+)
+
+// apiToken is the token of the api of the tests
+const apiToken = "s3cr3t"
+
+// requestAPI sends a request authenticated by the token of the api
+func requestAPI(method string, url string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+apiToken)
+	req.Header.Set("Content-Type", "application/json")
+
+	return http.DefaultClient.Do(req)
+}
+
+func TestAPI_Unauthorized(t *testing.T) {
+	api := API{Tokens: Tokens{apiToken}, Silencer: NewSilencer()}
+	server := httptest.NewServer(api.Handler())
+	defer server.Close()
+
+	cases := map[string]string{
+		"no token":             "",
+		"invalid token":        "Bearer guess",
+		"token without scheme": apiToken,
+	}
+
+	for name, authorization := range cases {
+		t.Run(name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, server.URL+"/api/silences",
+				strings.NewReader(`{"matchers": {"section": "checkout"}, "ends_at": "2100-01-01T00:00:00Z"}`))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Authorization", authorization)
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusUnauthorized {
+				t.Fatal("unexpected status", "expected", http.StatusUnauthorized, "actual", resp.StatusCode)
+			}
+			if len(api.Silencer.Silences()) != 0 {
+				t.Fatal("unexpected silence created")
+			}
+		})
+	}
+}
+
+func TestAPI_Silences(t *testing.T) {
+	api := API{Tokens: Tokens{apiToken}, Silencer: NewSilencer()}
+	server := httptest.NewServer(api.Handler())
+	defer server.Close()
+
+	// create
+	resp, err := requestAPI(http.MethodPost, server.URL+"/api/silences",
+		strings.NewReader(`{"matchers": {"section": "checkout"}, "ends_at": "2100-01-01T00:00:00Z", "comment": "deploy"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var created Silence
+	err = json.NewDecoder(resp.Body).Decode(&created)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusCreated || len(created.ID) == 0 {
+		t.Fatal("silence not created", "status", resp.StatusCode, "silence", created)
+	}
+
+	// list
+	resp, err = requestAPI(http.MethodGet, server.URL+"/api/silences", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var silences []Silence
+	err = json.NewDecoder(resp.Body).Decode(&silences)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(silences) != 1 || silences[0].ID != created.ID || silences[0].Comment != "deploy" {
+		t.Fatal("unexpected silences", "actual", silences)
+	}
+
+	// delete
+	resp, err = requestAPI(http.MethodDelete, server.URL+"/api/silences/"+created.ID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatal("silence not deleted", "status", resp.StatusCode)
+	}
+	if len(api.Silencer.Silences()) != 0 {
+		t.Fatal("silence still stored")
+	}
+
+	// invalid silence
+	resp, err = requestAPI(http.MethodPost, server.URL+"/api/silences", strings.NewReader(`{"comment": "no matchers"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatal("unexpected status", "expected", http.StatusBadRequest, "actual", resp.StatusCode)
+	}
+}
+
+func TestAPI_AlertsHistory(t *testing.T) {
+	journal, cleanup := setupJournal(t)
+	defer cleanup()
+
+	start := time.Date(2020, 02, 24, 2, 0, 0, 0, time.UTC)
+	for _, alert := range []Alert{
+		{Name: HighTrafficAlert, State: AlertFiring, TriggeredAt: start},
+		{Name: NoDataAlert, State: AlertFiring, TriggeredAt: start.Add(time.Minute)},
+		{Name: HighTrafficAlert, State: AlertResolved, TriggeredAt: start.Add(10 * time.Minute)},
+	} {
+		err := journal.Record(alert)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	api := API{Tokens: Tokens{apiToken}, Silencer: NewSilencer(), Journal: journal}
+	server := httptest.NewServer(api.Handler())
+	defer server.Close()
+
+	resp, err := requestAPI(http.MethodGet, server.URL+"/api/alerts/history?name=high_traffic&since=2020-02-24T02:05:00Z", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var alerts []Alert
+	err = json.NewDecoder(resp.Body).Decode(&alerts)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(alerts) != 1 || alerts[0].State != AlertResolved {
+		t.Fatal("unexpected alerts", "actual", alerts)
+	}
+
+	resp, err = requestAPI(http.MethodGet, server.URL+"/api/alerts/time-in-alert?until=2020-02-24T03:00:00Z", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var durations []AlertDuration
+	err = json.NewDecoder(resp.Body).Decode(&durations)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []AlertDuration{
+		{Name: NoDataAlert, Duration: 59 * time.Minute, Count: 1},
+		{Name: HighTrafficAlert, Duration: 10 * time.Minute, Count: 1},
+	}
+	if !reflect.DeepEqual(expected, durations) {
+		t.Fatal("unexpected time in alert", "expected", expected, "actual", durations)
+	}
+
+	resp, err = requestAPI(http.MethodGet, server.URL+"/api/alerts/history?since=yesterday", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatal("unexpected status", "expected", http.StatusBadRequest, "actual", resp.StatusCode)
+	}
+}
+
+func TestAPI_Statistics(t *testing.T) {
+	monitor := setupLogMonitor(t)
+	monitor.HandleEvent(commonlog.Event{Date: time.Now().Add(-10 * time.Second), Status: http.StatusOK, Section: "api", Source: "api.log"})
+
+	api := API{Tokens: Tokens{apiToken}, Silencer: NewSilencer(), Monitor: monitor, TopSectionsCount: 1}
+	server := httptest.NewServer(api.Handler())
+	defer server.Close()
+
+	resp, err := requestAPI(http.MethodGet, server.URL+"/api/statistics?source=api.log", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var statistics Statistics
+	err = json.NewDecoder(resp.Body).Decode(&statistics)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Section{{Name: "api", Hits: 1}}
+	if resp.StatusCode != http.StatusOK || !reflect.DeepEqual(expected, statistics.TopSections) {
+		t.Fatal("unexpected statistics", "expected", expected, "actual", statistics)
+	}
+
+	resp, err = requestAPI(http.MethodGet, server.URL+"/api/statistics?source=unknown.log", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatal("unexpected status", "expected", http.StatusNotFound, "actual", resp.StatusCode)
+	}
+}
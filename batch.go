@@ -0,0 +1,403 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"sort"
+	"time"
+
+	"github.com/ali.ghanem/http-log-monitoring/commonlog"
+	"github.com/ali.ghanem/http-log-monitoring/metric"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Modes of the monitor
+const (
+	FollowMode = "follow" // tails the logs while they are written
+	BatchMode  = "batch"  // reads the logs to their end and prints a report
+	ReplayMode = "replay" // reads the logs at the pace of their events and prints a report
+)
+
+// maxLineSize is the size of the longest line read from the logs
+const maxLineSize = 1024 * 1024
+
+// Compression formats detected by their magic number
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// logReader reads a log file, decompressed when needed
+type logReader struct {
+	io.Reader
+	close func() error
+}
+
+func (r *logReader) Close() error {
+	return r.close()
+}
+
+// openLog opens a log file or the standard input, it is decompressed when it is compressed with gzip or zstd
+func openLog(path string) (io.ReadCloser, error) {
+	var file io.ReadCloser = os.Stdin
+	if path != StdinPath {
+		var err error
+		file, err = os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	buffered := bufio.NewReader(file)
+	magic, _ := buffered.Peek(len(zstdMagic))
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		decompressed, err := gzip.NewReader(buffered)
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("cannot read gzip file %s: %w", path, err)
+		}
+		return &logReader{Reader: decompressed, close: func() error {
+			decompressed.Close()
+			return file.Close()
+		}}, nil
+	case bytes.HasPrefix(magic, zstdMagic):
+		decompressed, err := zstd.NewReader(buffered)
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("cannot read zstd file %s: %w", path, err)
+		}
+		return &logReader{Reader: decompressed, close: func() error {
+			decompressed.Close()
+			return file.Close()
+		}}, nil
+	default:
+		return &logReader{Reader: buffered, close: file.Close}, nil
+	}
+}
+
+// batchAnalyzer analyzes historical logs: the alerting rules are evaluated at the time of the events
+// and the alerts are recorded for the final report instead of being notified
+type batchAnalyzer struct {
+	config   Configuration
+	clock    *metric.EventClock
+	monitor  *LogMonitor
+	reporter *alertReporter
+	alerts   *alertRecorder
+
+	tasks    []*scheduledTask // tasks run at their interval of the events time
+	pacer    *pacer           // paces the events in replay mode, nil to analyze them as fast as possible
+	envelope *envelopeDecoder // unwraps the lines of the container logs, nil when they are not wrapped
+	parse    commonlog.Parser
+
+	deadLetter *DeadLetter // records the rejected lines, nil when there is no dead letter file
+
+	lines    int64
+	rejected int64
+	first    time.Time
+}
+
+// scheduledTask is run every interval of the events time
+type scheduledTask struct {
+	interval time.Duration
+	next     time.Time
+	run      func()
+}
+
+func newBatchAnalyzer(config Configuration) *batchAnalyzer {
+	clock := metric.NewEventClock()
+	alerts := &alertRecorder{}
+	// the format is validated by the configuration
+	parse, _ := commonlog.ParserFor(config.LogFormat)
+	b := &batchAnalyzer{
+		config:   config,
+		parse:    parse,
+		clock:    clock,
+		monitor:  newMonitor(config, clock),
+		reporter: newAlertReporter(alerts, nil, config.AlertRepeatInterval),
+		alerts:   alerts,
+	}
+
+	b.tasks = []*scheduledTask{
+		{interval: config.TrafficLoadCheckInterval, run: func() { checkAlerts(b.monitor, b.config, b.reporter) }},
+		{interval: config.CleaningInterval, run: func() { cleanTimeSeries(b.monitor, b.config) }},
+	}
+	if config.LogEnvelope != NoEnvelope {
+		b.envelope = newEnvelopeDecoder(config.LogEnvelope)
+	}
+	if config.Mode == ReplayMode {
+		b.pacer = newPacer(config.ReplaySpeed)
+		b.tasks = append(b.tasks, &scheduledTask{
+			interval: config.StatsDisplayInterval,
+			run:      func() { displayStatistics(b.monitor.Statistics(b.config.StatsTopSectionsCount)) },
+		})
+	}
+
+	return b
+}
+
+// runBatch analyzes the logs configured to their end and prints the report.
+// In replay mode, an interruption stops the replay and prints the report of the events already replayed.
+func runBatch(config Configuration) error {
+	files, err := matchFiles(config.LogsToMonitor)
+	if err != nil {
+		return err
+	}
+
+	analyzer := newBatchAnalyzer(config)
+	err = analyzer.sortByFirstEvent(files)
+	if err != nil {
+		return err
+	}
+	analyzer.deadLetter, err = openDeadLetter(config)
+	if err != nil {
+		return err
+	}
+	if analyzer.deadLetter != nil {
+		defer func() {
+			err := analyzer.deadLetter.Close()
+			if err != nil {
+				log.Println("failed to close dead letter file", "err", err)
+			}
+		}()
+	}
+	if analyzer.pacer != nil {
+		c := make(chan os.Signal, 1)
+		signal.Notify(c, os.Interrupt)
+		defer signal.Stop(c)
+
+		stop := make(chan struct{})
+		go func() {
+			<-c
+			log.Println("stopping replay")
+			close(stop)
+		}()
+		analyzer.pacer.stop = stop
+	}
+
+	for _, file := range files {
+		log.Println("analyzing log file", "file", file)
+		err := analyzer.analyze(file)
+		if err == errReplayStopped {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	analyzer.finish()
+	analyzer.report()
+	return nil
+}
+
+// sortByFirstEvent orders the files by the date of their first event so the events time only moves forward,
+// the rotated files matched after the current file are analyzed before it.
+// The standard input cannot be read twice, the order is kept when it is analyzed.
+func (b *batchAnalyzer) sortByFirstEvent(files []string) error {
+	firsts := make(map[string]time.Time, len(files))
+	for _, file := range files {
+		if file == StdinPath {
+			return nil
+		}
+
+		first, err := b.firstEvent(file)
+		if err != nil {
+			return err
+		}
+		firsts[file] = first
+	}
+
+	sort.SliceStable(files, func(i, j int) bool {
+		return firsts[files[i]].Before(firsts[files[j]])
+	})
+	return nil
+}
+
+// firstEvent returns the date of the first event of the file, zero when no line can be parsed
+func (b *batchAnalyzer) firstEvent(path string) (time.Time, error) {
+	reader, err := openLog(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer reader.Close()
+
+	var envelope *envelopeDecoder
+	if b.envelope != nil {
+		envelope = newEnvelopeDecoder(b.config.LogEnvelope)
+	}
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	for scanner.Scan() {
+		line := LogLine{Source: path, Text: scanner.Text()}
+		if envelope != nil {
+			var complete bool
+			line, complete = envelope.decode(line)
+			if !complete || line.Err != nil {
+				continue
+			}
+		}
+
+		event, err := b.parse(line.Text)
+		if err == nil {
+			return event.Date, nil
+		}
+	}
+
+	err = scanner.Err()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("cannot read log file %s: %w", path, err)
+	}
+	return time.Time{}, nil
+}
+
+// analyze reads the file to its end
+func (b *batchAnalyzer) analyze(path string) error {
+	reader, err := openLog(path)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	for scanner.Scan() {
+		if !b.handle(LogLine{Source: path, Text: scanner.Text()}) {
+			return errReplayStopped
+		}
+	}
+
+	err = scanner.Err()
+	if err != nil {
+		return fmt.Errorf("cannot read log file %s: %w", path, err)
+	}
+	return nil
+}
+
+// handle parses the line and runs the tasks whose interval elapsed before its event.
+// It returns false when the replay is stopped.
+func (b *batchAnalyzer) handle(line LogLine) bool {
+	if b.envelope != nil {
+		var complete bool
+		line, complete = b.envelope.decode(line)
+		if !complete {
+			return true
+		}
+	}
+	if line.Err != nil {
+		b.lines++
+		b.rejected++
+		log.Println("cannot read line", "err", line.Err, "source", line.Source, "line", line.Text)
+		rejectLine(b.monitor, b.deadLetter, line, UnreadableReason, line.Err)
+		return true
+	}
+
+	event, err := b.parse(line.Text)
+	if errors.Is(err, commonlog.ErrHeader) {
+		return true
+	}
+	if err != nil {
+		b.lines++
+		b.rejected++
+		log.Println("cannot parse line", "err", err, "source", line.Source, "line", line.Text)
+		rejectLine(b.monitor, b.deadLetter, line, commonlog.Reason(err), err)
+		return true
+	}
+	event.Source = line.Source
+	event.Labels = line.Labels
+
+	if b.pacer != nil && !b.pacer.pace(event.Date) {
+		return false
+	}
+
+	b.lines++
+	b.advance(event.Date)
+	b.monitor.LineReceived(event.Date)
+	b.monitor.HandleEvent(event)
+	return true
+}
+
+// advance moves the clock to the date, the scheduled tasks are run at their intervals
+func (b *batchAnalyzer) advance(date time.Time) {
+	if b.first.IsZero() {
+		b.first = date
+		b.clock.Advance(date)
+		for _, task := range b.tasks {
+			task.next = date.Add(task.interval)
+		}
+		return
+	}
+
+	for {
+		var due *scheduledTask
+		for _, task := range b.tasks {
+			if task.next.After(date) {
+				continue
+			}
+			if due == nil || task.next.Before(due.next) {
+				due = task
+			}
+		}
+		if due == nil {
+			break
+		}
+
+		b.clock.Advance(due.next)
+		due.run()
+		due.next = due.next.Add(due.interval)
+	}
+
+	b.clock.Advance(date)
+}
+
+// finish runs the tasks due until one check interval after the last event,
+// so the traffic of the last interval is evaluated before the report
+func (b *batchAnalyzer) finish() {
+	if b.first.IsZero() {
+		return
+	}
+	b.advance(b.clock.Now().Add(b.config.TrafficLoadCheckInterval))
+}
+
+// report prints the statistics and the alerts of the period analyzed
+func (b *batchAnalyzer) report() {
+	end := b.clock.Now()
+	log.Println(fmt.Sprintf("analysis finished - lines: %v - rejected: %v - from %s to %s", b.lines, b.rejected, b.first, end))
+	displayStatistics(b.monitor.Statistics(b.config.StatsTopSectionsCount))
+
+	var fired int
+	for _, alert := range b.alerts.alerts {
+		if alert.State == AlertFiring {
+			fired++
+		}
+	}
+	log.Println("alerts fired during the period", fired)
+	for _, alert := range b.alerts.alerts {
+		log.Println(fmt.Sprintf("%s alert %s at %s - %s", alert.Name, alert.State, alert.TriggeredAt, alert.Description()))
+	}
+	for _, duration := range TimeInAlert(b.alerts.alerts, end) {
+		subject := Alert{Scope: duration.Scope, Key: duration.Key}.Subject()
+		log.Println(fmt.Sprintf("%s alert on %s - time in alert: %s - fired: %v", duration.Name, subject, duration.Duration, duration.Count))
+	}
+}
+
+// alertRecorder records the alerts notified
+type alertRecorder struct {
+	alerts []Alert
+}
+
+func (r *alertRecorder) Notify(alert Alert) error {
+	r.alerts = append(r.alerts, alert)
+	return nil
+}
+
+func (r *alertRecorder) Close() error {
+	return nil
+}
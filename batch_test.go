@@ -0,0 +1,254 @@
+package main
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ali.ghanem/http-log-monitoring/commonlog"
+	"github.com/klauspost/compress/zstd"
+)
+
+// writeTrafficLog writes ten minutes of traffic with a peak of 20 requests / second
+// between the third and the sixth minute, and a line that cannot be parsed
+func writeTrafficLog(t *testing.T, writer io.Writer, start time.Time) {
+	for second := 0; second < 600; second++ {
+		rate := 1
+		if second >= 180 && second < 360 {
+			rate = 20
+		}
+
+		date := start.Add(time.Duration(second) * time.Second).Format("02/Jan/2006:15:04:05 -0700")
+		for i := 0; i < rate; i++ {
+			_, err := fmt.Fprintf(writer, "127.0.0.1 - james [%s] \"GET /report HTTP/1.0\" 200 123\n", date)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	_, err := fmt.Fprintln(writer, "not a log line")
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBatchAnalyzer_Analyze(t *testing.T) {
+	type testCase struct {
+		Compress func(io.Writer) (io.WriteCloser, error)
+	}
+
+	cases := map[string]testCase{
+		"plain": {
+			Compress: nil,
+		},
+		"gzip": {
+			Compress: func(w io.Writer) (io.WriteCloser, error) {
+				return gzip.NewWriter(w), nil
+			},
+		},
+		"zstd": {
+			Compress: func(w io.Writer) (io.WriteCloser, error) {
+				return zstd.NewWriter(w)
+			},
+		},
+	}
+
+	start := time.Date(2018, 5, 9, 16, 0, 0, 0, time.UTC)
+	config := Configuration{
+		LogFormat:                commonlog.CommonFormat,
+		LogEnvelope:              NoEnvelope,
+		StatsDisplayInterval:     time.Minute,
+		StatsTopSectionsCount:    10,
+		TrafficLoadCheckInterval: 10 * time.Second,
+		TrafficLoadPeriod:        2 * time.Minute,
+		TrafficThreshold:         10,
+		SLOWindow:                DefaultSLOWindow,
+		CleaningInterval:         time.Minute,
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "batch")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+
+			path := filepath.Join(dir, "access.log")
+			file, err := os.Create(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var writer io.WriteCloser = file
+			if c.Compress != nil {
+				writer, err = c.Compress(file)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			writeTrafficLog(t, writer, start)
+			if c.Compress != nil {
+				err = writer.Close()
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			file.Close()
+
+			analyzer := newBatchAnalyzer(config)
+			err = analyzer.analyze(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if analyzer.lines != 4021 || analyzer.rejected != 1 {
+				t.Fatal("unexpected lines", "expected", 4021, 1, "actual", analyzer.lines, analyzer.rejected)
+			}
+
+			end := start.Add(599 * time.Second)
+			if !analyzer.clock.Now().Equal(end) {
+				t.Fatal("unexpected clock", "expected", end, "actual", analyzer.clock.Now())
+			}
+
+			// the alert is evaluated at the time of the events
+			alerts := analyzer.alerts.alerts
+			if len(alerts) != 2 {
+				t.Fatal("unexpected alerts", "expected", 2, "actual", alerts)
+			}
+			expectedFiring := start.Add(240 * time.Second)
+			if alerts[0].Name != HighTrafficAlert || alerts[0].State != AlertFiring || !alerts[0].TriggeredAt.Equal(expectedFiring) {
+				t.Fatal("unexpected firing alert", "expected", expectedFiring, "actual", alerts[0])
+			}
+			expectedResolved := start.Add(430 * time.Second)
+			if alerts[1].Name != HighTrafficAlert || alerts[1].State != AlertResolved || !alerts[1].TriggeredAt.Equal(expectedResolved) {
+				t.Fatal("unexpected resolved alert", "expected", expectedResolved, "actual", alerts[1])
+			}
+		})
+	}
+}
+
+func TestBatchAnalyzer_SortByFirstEvent(t *testing.T) {
+	dir, err := ioutil.TempDir("", "batch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// the current file is matched before the rotated one which holds the older events
+	start := time.Date(2018, 5, 9, 16, 0, 0, 0, time.UTC)
+	for path, date := range map[string]time.Time{"access.log": start.Add(10 * time.Minute), "access.log.1": start} {
+		file, err := os.Create(filepath.Join(dir, path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		writeTrafficLog(t, file, date)
+		file.Close()
+	}
+
+	config := Configuration{
+		LogFormat:                commonlog.CommonFormat,
+		LogEnvelope:              NoEnvelope,
+		StatsTopSectionsCount:    10,
+		TrafficLoadCheckInterval: 10 * time.Second,
+		TrafficLoadPeriod:        2 * time.Minute,
+		TrafficThreshold:         10,
+		SLOWindow:                DefaultSLOWindow,
+		CleaningInterval:         time.Minute,
+	}
+	files, err := matchFiles([]string{filepath.Join(dir, "access.log*")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	analyzer := newBatchAnalyzer(config)
+	err = analyzer.sortByFirstEvent(files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedFiles := []string{filepath.Join(dir, "access.log.1"), filepath.Join(dir, "access.log")}
+	if !reflect.DeepEqual(expectedFiles, files) {
+		t.Fatal("unexpected files", "expected", expectedFiles, "actual", files)
+	}
+	for _, file := range files {
+		err = analyzer.analyze(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the peak of each file is alerted
+	expected := []time.Duration{240 * time.Second, 430 * time.Second, 840 * time.Second, 1030 * time.Second}
+	alerts := analyzer.alerts.alerts
+	if len(alerts) != len(expected) {
+		t.Fatal("unexpected alerts", "expected", len(expected), "actual", alerts)
+	}
+	for i, alert := range alerts {
+		if !alert.TriggeredAt.Equal(start.Add(expected[i])) {
+			t.Fatal("unexpected alert", "expected", start.Add(expected[i]), "actual", alert)
+		}
+	}
+}
+
+func TestBatchAnalyzer_Finish(t *testing.T) {
+	dir, err := ioutil.TempDir("", "batch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// the traffic crosses the threshold only with the burst of the last seconds
+	start := time.Date(2018, 5, 9, 16, 0, 0, 0, time.UTC)
+	path := filepath.Join(dir, "access.log")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for second := 0; second < 300; second++ {
+		rate := 1
+		if second >= 295 {
+			rate = 300
+		}
+
+		date := start.Add(time.Duration(second) * time.Second).Format("02/Jan/2006:15:04:05 -0700")
+		for i := 0; i < rate; i++ {
+			_, err := fmt.Fprintf(file, "127.0.0.1 - james [%s] \"GET /report HTTP/1.0\" 200 123\n", date)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	file.Close()
+
+	config := Configuration{
+		LogFormat:                commonlog.CommonFormat,
+		LogEnvelope:              NoEnvelope,
+		StatsTopSectionsCount:    10,
+		TrafficLoadCheckInterval: 10 * time.Second,
+		TrafficLoadPeriod:        2 * time.Minute,
+		TrafficThreshold:         10,
+		SLOWindow:                DefaultSLOWindow,
+		CleaningInterval:         time.Minute,
+	}
+	analyzer := newBatchAnalyzer(config)
+	err = analyzer.analyze(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(analyzer.alerts.alerts) != 0 {
+		t.Fatal("unexpected alerts before the end", "actual", analyzer.alerts.alerts)
+	}
+
+	// the last interval is checked once the analysis is finished
+	analyzer.finish()
+	alerts := analyzer.alerts.alerts
+	expectedFiring := start.Add(300 * time.Second)
+	if len(alerts) != 1 || alerts[0].State != AlertFiring || !alerts[0].TriggeredAt.Equal(expectedFiring) {
+		t.Fatal("unexpected alerts", "expected", expectedFiring, "actual", alerts)
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// FilePosition locates the end of the last line read in a file
+type FilePosition struct {
+	Inode  uint64 `json:"inode"`
+	Device uint64 `json:"device"`
+	Offset int64  `json:"offset"`
+}
+
+// Checkpoints stores the position reached in each monitored file so a restart resumes where it stopped
+type Checkpoints struct {
+	sync.Mutex
+	path      string
+	positions map[string]FilePosition // positions by file path
+	updated   bool                    // positions updated since the last save
+}
+
+// checkpointsFile is the format of the checkpoint file
+type checkpointsFile struct {
+	Files map[string]FilePosition `json:"files"`
+}
+
+// LoadCheckpoints reads the checkpoint file, no position is known when the file does not exist yet
+func LoadCheckpoints(path string) (*Checkpoints, error) {
+	checkpoints := &Checkpoints{
+		path:      path,
+		positions: make(map[string]FilePosition),
+	}
+
+	content, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return checkpoints, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var file checkpointsFile
+	err = json.Unmarshal(content, &file)
+	if err != nil {
+		return nil, err
+	}
+	for name, position := range file.Files {
+		checkpoints.positions[name] = position
+	}
+
+	return checkpoints, nil
+}
+
+// Position returns the position reached in the file
+func (c *Checkpoints) Position(file string) (FilePosition, bool) {
+	c.Lock()
+	defer c.Unlock()
+
+	position, ok := c.positions[file]
+	return position, ok
+}
+
+// Update records the position reached in the file once its line has been handled
+func (c *Checkpoints) Update(file string, position FilePosition) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.positions[file] = position
+	c.updated = true
+}
+
+// Save writes the positions in the checkpoint file when they changed.
+// The file is replaced atomically so a crash never leaves a partial checkpoint.
+func (c *Checkpoints) Save() error {
+	c.Lock()
+	defer c.Unlock()
+
+	if !c.updated {
+		return nil
+	}
+
+	content, err := json.Marshal(checkpointsFile{Files: c.positions})
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(c.path), filepath.Base(c.path)+".tmp")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(content)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), c.path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	c.updated = false
+	return nil
+}
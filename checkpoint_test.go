@@ -0,0 +1,52 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckpoints_Save(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checkpoints")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "checkpoints.json")
+
+	// no checkpoint file yet
+	checkpoints, err := LoadCheckpoints(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := checkpoints.Position("access.log"); ok {
+		t.Fatal("unexpected position")
+	}
+
+	expected := FilePosition{Inode: 42, Device: 2049, Offset: 1024}
+	checkpoints.Update("access.log", expected)
+	err = checkpoints.Save()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checkpoints, err = LoadCheckpoints(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual, ok := checkpoints.Position("access.log")
+	if !ok || expected != actual {
+		t.Fatal("unexpected position", "expected", expected, "actual", actual)
+	}
+
+	// no temporary file left
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Fatal("unexpected files", "expected", 1, "actual", len(files))
+	}
+}
@@ -0,0 +1,184 @@
+package commonlog
+
+import (
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Extra fields of the load balancers and CDN logs
+const (
+	TargetField                 = "target"
+	TargetStatusField           = "target_status_code"
+	RequestProcessingTimeField  = "request_processing_time"
+	TargetProcessingTimeField   = "target_processing_time"
+	ResponseProcessingTimeField = "response_processing_time"
+	LoadBalancerField           = "load_balancer"
+	UserAgentField              = "user_agent"
+	TraceIDField                = "trace_id"
+	TypeField                   = "type"
+)
+
+// ParseALB parses a line of an AWS application load balancer access log:
+// type time elb client:port target:port request_processing_time target_processing_time response_processing_time
+// elb_status_code target_status_code received_bytes sent_bytes "request" "user_agent" ssl_cipher ssl_protocol
+// target_group_arn "trace_id" ...
+func ParseALB(line string) (Event, error) {
+	fields, err := splitQuoted(line)
+	if err != nil {
+		return Event{}, err
+	}
+	if len(fields) < 18 {
+		return Event{}, parseError(ErrMissingField, "missing fields: %v fields - expected at least 18", len(fields))
+	}
+
+	event, err := parseAWS(fields[1:])
+	if err != nil {
+		return event, err
+	}
+	event.Fields[TypeField] = fields[0]
+	event.Fields[TraceIDField] = fields[17]
+	return event, nil
+}
+
+// ParseELB parses a line of an AWS classic load balancer access log:
+// time elb client:port backend:port request_processing_time backend_processing_time response_processing_time
+// elb_status_code backend_status_code received_bytes sent_bytes "request" "user_agent" ssl_cipher ssl_protocol
+func ParseELB(line string) (Event, error) {
+	fields, err := splitQuoted(line)
+	if err != nil {
+		return Event{}, err
+	}
+	if len(fields) < 13 {
+		return Event{}, parseError(ErrMissingField, "missing fields: %v fields - expected at least 13", len(fields))
+	}
+
+	return parseAWS(fields)
+}
+
+// parseAWS parses the fields shared by the application and the classic load balancers
+func parseAWS(fields []string) (event Event, err error) {
+	event.Date, err = time.Parse(time.RFC3339Nano, fields[0])
+	if err != nil {
+		return event, parseError(ErrBadDate, "invalid date format: %w", err)
+	}
+
+	event.Host = fields[2]
+	if host, _, err := net.SplitHostPort(fields[2]); err == nil {
+		event.Host = host
+	}
+	event.RFC931 = "-"
+	event.User = "-"
+
+	event.Status, err = strconv.Atoi(fields[7])
+	if err != nil {
+		return event, parseError(ErrBadStatus, "invalid status format: %w", err)
+	}
+	event.Bytes, err = strconv.Atoi(fields[10])
+	if err != nil {
+		return event, parseError(ErrBadBytes, "invalid bytes number: %w", err)
+	}
+
+	event.Request, err = requestPath(fields[11])
+	if err != nil {
+		return event, err
+	}
+	event.Section, err = ParseSection(event.Request)
+	if err != nil {
+		return event, err
+	}
+
+	// the processing times are -1 when the request did not reach a target
+	var total float64
+	complete := true
+	for _, value := range fields[4:7] {
+		seconds, err := strconv.ParseFloat(value, 64)
+		if err != nil || seconds < 0 {
+			complete = false
+			break
+		}
+		total += seconds
+	}
+	if complete {
+		event.Duration = time.Duration(total * float64(time.Second))
+		event.HasDuration = true
+	}
+
+	event.Fields = map[string]string{
+		LoadBalancerField:           fields[1],
+		TargetField:                 fields[3],
+		RequestProcessingTimeField:  fields[4],
+		TargetProcessingTimeField:   fields[5],
+		ResponseProcessingTimeField: fields[6],
+		TargetStatusField:           fields[8],
+		UserAgentField:              fields[12],
+	}
+	return event, nil
+}
+
+// requestPath replaces the absolute url of a request line by its path: "GET http://host:80/path HTTP/1.1"
+// becomes "GET /path HTTP/1.1"
+func requestPath(request string) (string, error) {
+	parts := strings.SplitN(request, " ", 3)
+	if len(parts) < 2 {
+		return "", parseError(ErrBadRequest, "invalid request: %s", request)
+	}
+
+	target, err := url.Parse(parts[1])
+	if err != nil {
+		return "", parseError(ErrBadRequest, "invalid request url: %w", err)
+	}
+	if target.IsAbs() {
+		parts[1] = target.RequestURI()
+	}
+	return strings.Join(parts, " "), nil
+}
+
+// splitQuoted splits the fields separated by spaces, the quoted fields may contain spaces and escaped quotes
+func splitQuoted(line string) ([]string, error) {
+	if len(line) == 0 {
+		return nil, ErrEmptyLine
+	}
+
+	var fields []string
+	for i := 0; i < len(line); {
+		if line[i] == ' ' {
+			i++
+			continue
+		}
+
+		if line[i] != '"' {
+			end := strings.IndexByte(line[i:], ' ')
+			if end < 0 {
+				end = len(line) - i
+			}
+			fields = append(fields, line[i:i+end])
+			i += end
+			continue
+		}
+
+		var field strings.Builder
+		closed := false
+		for i++; i < len(line); i++ {
+			if line[i] == '\\' && i+1 < len(line) {
+				i++
+				field.WriteByte(line[i])
+				continue
+			}
+			if line[i] == '"' {
+				closed = true
+				i++
+				break
+			}
+			field.WriteByte(line[i])
+		}
+		if !closed {
+			return nil, parseError(ErrMalformed, "unterminated quoted field")
+		}
+		fields = append(fields, field.String())
+	}
+
+	return fields, nil
+}
@@ -0,0 +1,85 @@
+package commonlog
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Extra fields of the CloudFront logs
+const (
+	EdgeLocationField           = "edge_location"
+	EdgeResultTypeField         = "edge_result_type"
+	EdgeResponseResultTypeField = "edge_response_result_type"
+	TimeToFirstByteField        = "time_to_first_byte"
+)
+
+// cloudFrontTimeLayout is the layout of the date and the time fields of the CloudFront logs, in UTC
+const cloudFrontTimeLayout = "2006-01-02 15:04:05"
+
+// ParseCloudFront parses a line of a CloudFront standard log, its fields are separated by tabs:
+// date time x-edge-location sc-bytes c-ip cs-method cs(Host) cs-uri-stem sc-status cs(Referer) cs(User-Agent)
+// cs-uri-query cs(Cookie) x-edge-result-type x-edge-request-id x-host-header cs-protocol cs-bytes time-taken
+// x-forwarded-for ssl-protocol ssl-cipher x-edge-response-result-type cs-protocol-version ...
+// The lines starting with # are the headers of the log.
+func ParseCloudFront(line string) (event Event, err error) {
+	if strings.HasPrefix(line, "#") {
+		return event, ErrHeader
+	}
+
+	fields := strings.Split(line, "\t")
+	if len(fields) < 19 {
+		return event, parseError(ErrMissingField, "missing fields: %v fields - expected at least 19", len(fields))
+	}
+
+	event.Date, err = time.Parse(cloudFrontTimeLayout, fields[0]+" "+fields[1])
+	if err != nil {
+		return event, parseError(ErrBadDate, "invalid date format: %w", err)
+	}
+
+	event.Host = fields[4]
+	event.RFC931 = "-"
+	event.User = "-"
+
+	event.Bytes, err = strconv.Atoi(fields[3])
+	if err != nil {
+		return event, parseError(ErrBadBytes, "invalid bytes number: %w", err)
+	}
+	event.Status, err = strconv.Atoi(fields[8])
+	if err != nil {
+		return event, parseError(ErrBadStatus, "invalid status format: %w", err)
+	}
+
+	path := fields[7]
+	if fields[11] != "-" {
+		path += "?" + fields[11]
+	}
+	event.Request = fields[5] + " " + path
+	if len(fields) > 23 && fields[23] != "-" {
+		event.Request += " " + fields[23]
+	}
+	// the stem is followed by a space so its first segment is found when it is the whole path
+	event.Section, err = ParseSection(fields[7] + " ")
+	if err != nil {
+		return event, err
+	}
+
+	seconds, err := strconv.ParseFloat(fields[18], 64)
+	if err == nil && seconds >= 0 {
+		event.Duration = time.Duration(seconds * float64(time.Second))
+		event.HasDuration = true
+	}
+
+	event.Fields = map[string]string{
+		EdgeLocationField:   fields[2],
+		EdgeResultTypeField: fields[13],
+		UserAgentField:      fields[10],
+	}
+	if len(fields) > 22 {
+		event.Fields[EdgeResponseResultTypeField] = fields[22]
+	}
+	if len(fields) > 27 {
+		event.Fields[TimeToFirstByteField] = fields[27]
+	}
+	return event, nil
+}
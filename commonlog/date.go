@@ -0,0 +1,105 @@
+package commonlog
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Abbreviated month names of the dates
+var shortMonthNames = [12]string{"Jan", "Feb", "Mar", "Apr", "May", "Jun", "Jul", "Aug", "Sep", "Oct", "Nov", "Dec"}
+
+// maxOffset is the maximum time zone offset accepted by time.Parse, 24 hours and 60 minutes
+const maxOffset = 24*60 + 60
+
+// Locations of the time zone offsets by minute, created on their first use
+var fixedZones [2*maxOffset + 1]atomic.Value
+
+// decodeDate decodes a date of the layout 02/Jan/2006:15:04:05 -0700 without allocating.
+// It accepts the same dates as time.Parse and returns them in the same location:
+// the local one when the offset is the local offset at that date, a fixed zone otherwise.
+func decodeDate(value []byte) (time.Time, error) {
+	if len(value) != len(timeLayout) || value[2] != '/' || value[6] != '/' || value[11] != ':' ||
+		value[14] != ':' || value[17] != ':' || value[20] != ' ' {
+		return time.Time{}, ErrBadDate
+	}
+
+	day, ok1 := digits(value[0:2])
+	month, ok2 := decodeMonth(value[3:6])
+	year, ok3 := digits(value[7:11])
+	hour, ok4 := digits(value[12:14])
+	minute, ok5 := digits(value[15:17])
+	second, ok6 := digits(value[18:20])
+	offsetHours, ok7 := digits(value[22:24])
+	offsetMinutes, ok8 := digits(value[24:26])
+	if !(ok1 && ok2 && ok3 && ok4 && ok5 && ok6 && ok7 && ok8) {
+		return time.Time{}, ErrBadDate
+	}
+	if day < 1 || day > daysIn(month, year) || hour >= 24 || minute >= 60 || second >= 60 || offsetHours > 24 || offsetMinutes > 60 {
+		return time.Time{}, ErrBadDate
+	}
+
+	offset := offsetHours*60 + offsetMinutes
+	switch value[21] {
+	case '+':
+	case '-':
+		offset = -offset
+	default:
+		return time.Time{}, ErrBadDate
+	}
+
+	date := time.Date(year, month, day, hour, minute, second, 0, time.UTC).Add(time.Duration(-offset) * time.Minute)
+	local := date.In(time.Local)
+	if _, localOffset := local.Zone(); localOffset == offset*60 {
+		return local, nil
+	}
+	return date.In(fixedZone(offset)), nil
+}
+
+// fixedZone returns the location of a time zone offset in minutes
+func fixedZone(offset int) *time.Location {
+	zone := &fixedZones[offset+maxOffset]
+	if location, ok := zone.Load().(*time.Location); ok {
+		return location
+	}
+
+	location := time.FixedZone("", offset*60)
+	zone.Store(location)
+	return location
+}
+
+// decodeMonth returns the month of its abbreviated name, case insensitive like time.Parse
+func decodeMonth(value []byte) (time.Month, bool) {
+	for i, name := range shortMonthNames {
+		if value[0]|0x20 == name[0]|0x20 && value[1]|0x20 == name[1] && value[2]|0x20 == name[2] {
+			return time.Month(i + 1), true
+		}
+	}
+	return 0, false
+}
+
+// maxDigits is the length of the longest number read by digits which cannot overflow an int64
+const maxDigits = 18
+
+// digits returns the number written with the decimal digits only, the longer numbers are rejected
+func digits(value []byte) (int, bool) {
+	if len(value) == 0 || len(value) > maxDigits {
+		return 0, false
+	}
+
+	n := 0
+	for _, c := range value {
+		if c < '0' || c > '9' {
+			return 0, false
+		}
+		n = n*10 + int(c-'0')
+	}
+	return n, true
+}
+
+// daysIn returns the number of days of the month
+func daysIn(month time.Month, year int) int {
+	if month == time.February && year%4 == 0 && (year%100 != 0 || year%400 == 0) {
+		return 29
+	}
+	return [...]int{31, 28, 31, 30, 31, 30, 31, 31, 30, 31, 30, 31}[month-1]
+}
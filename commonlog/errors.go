@@ -0,0 +1,76 @@
+package commonlog
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Reasons of the lines rejected by the parsers, they can be checked with errors.Is
+var (
+	ErrEmptyLine    = errors.New("empty log line")
+	ErrMissingField = errors.New("missing field")
+	ErrMalformed    = errors.New("malformed line") // the structure of the line is invalid, such as a json entry
+	ErrBadDate      = errors.New("invalid date")
+	ErrBadRequest   = errors.New("invalid request")
+	ErrBadStatus    = errors.New("invalid status")
+	ErrBadBytes     = errors.New("invalid bytes number")
+	ErrNoSection    = errors.New("section not found")
+
+	// ErrHeader is returned for the header lines of a log, such as the "#Fields" line of the W3C logs.
+	// They describe the log and are not events.
+	ErrHeader = errors.New("header line")
+)
+
+// Names of the reasons counted in the statistics
+var reasons = []struct {
+	err  error
+	name string
+}{
+	{ErrEmptyLine, "empty_line"},
+	{ErrMissingField, "missing_field"},
+	{ErrMalformed, "malformed"},
+	{ErrBadDate, "bad_date"},
+	{ErrBadRequest, "bad_request"},
+	{ErrBadStatus, "bad_status"},
+	{ErrBadBytes, "bad_bytes"},
+	{ErrNoSection, "no_section"},
+	{ErrHeader, "header"},
+}
+
+// OtherReason is the reason of the errors which are not parse errors
+const OtherReason = "other"
+
+// ParseError describes why a line is rejected, its reason is one of the errors of the package
+type ParseError struct {
+	Reason error
+	Err    error
+}
+
+// parseError returns an error of the reason described by the message
+func parseError(reason error, format string, args ...interface{}) error {
+	return &ParseError{Reason: reason, Err: fmt.Errorf(format, args...)}
+}
+
+func (e *ParseError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the cause of the error, such as the error of time.Parse
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// Is tells whether the error has the reason
+func (e *ParseError) Is(target error) bool {
+	return e.Reason == target
+}
+
+// Reason returns the name of the reason of a parse error, OtherReason for the other errors
+func Reason(err error) string {
+	for _, reason := range reasons {
+		if errors.Is(err, reason.err) {
+			return reason.name
+		}
+	}
+	return OtherReason
+}
@@ -0,0 +1,123 @@
+package commonlog_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/ali.ghanem/http-log-monitoring/commonlog"
+)
+
+func TestReason(t *testing.T) {
+	type testCase struct {
+		Format         string
+		Line           string
+		ExpectedError  error
+		ExpectedReason string
+	}
+
+	cases := map[string]testCase{
+		"empty line": {
+			Format:         commonlog.CommonFormat,
+			Line:           "",
+			ExpectedError:  commonlog.ErrEmptyLine,
+			ExpectedReason: "empty_line",
+		},
+		"missing date": {
+			Format:         commonlog.CommonFormat,
+			Line:           `66.137.220.245 rfc user[10/Feb/2020:17:35:21 +0100]`,
+			ExpectedError:  commonlog.ErrMissingField,
+			ExpectedReason: "missing_field",
+		},
+		"invalid date": {
+			Format:         commonlog.CommonFormat,
+			Line:           `66.137.220.245 rfc user [10/Inv/2020:17:35:21 +0100] "GET /api HTTP/1.1" 200 512`,
+			ExpectedError:  commonlog.ErrBadDate,
+			ExpectedReason: "bad_date",
+		},
+		"no section": {
+			Format:         commonlog.CommonFormat,
+			Line:           `66.137.220.245 - - [10/Feb/2020:17:35:21 +0100] "GET / HTTP/1.1" 200 512`,
+			ExpectedError:  commonlog.ErrNoSection,
+			ExpectedReason: "no_section",
+		},
+		"invalid status": {
+			Format:         commonlog.CommonFormat,
+			Line:           `66.137.220.245 - - [10/Feb/2020:17:35:21 +0100] "GET /api HTTP/1.1" XXX 512`,
+			ExpectedError:  commonlog.ErrBadStatus,
+			ExpectedReason: "bad_status",
+		},
+		"invalid bytes number": {
+			Format:         commonlog.CommonFormat,
+			Line:           `66.137.220.245 - - [10/Feb/2020:17:35:21 +0100] "GET /api HTTP/1.1" 200 UUUUU`,
+			ExpectedError:  commonlog.ErrBadBytes,
+			ExpectedReason: "bad_bytes",
+		},
+		"status overflow": {
+			Format:         commonlog.CommonFormat,
+			Line:           `66.137.220.245 - - [10/Feb/2020:17:35:21 +0100] "GET /api HTTP/1.1" 99999999999999999999 512`,
+			ExpectedError:  commonlog.ErrBadStatus,
+			ExpectedReason: "bad_status",
+		},
+		"bytes number overflow": {
+			Format:         commonlog.CommonFormat,
+			Line:           `66.137.220.245 - - [10/Feb/2020:17:35:21 +0100] "GET /api HTTP/1.1" 200 99999999999999999999999`,
+			ExpectedError:  commonlog.ErrBadBytes,
+			ExpectedReason: "bad_bytes",
+		},
+		"invalid json entry": {
+			Format:         commonlog.GCPFormat,
+			Line:           `{"httpRequest":`,
+			ExpectedError:  commonlog.ErrMalformed,
+			ExpectedReason: "malformed",
+		},
+		"missing proxy fields": {
+			Format:         commonlog.HAProxyFormat,
+			Line:           `haproxy[14389]: 10.0.1.2:33317 [06/Feb/2009:12:14:14.655] http-in static/srv1`,
+			ExpectedError:  commonlog.ErrMissingField,
+			ExpectedReason: "missing_field",
+		},
+		"header line": {
+			Format:         commonlog.CloudFrontFormat,
+			Line:           "#Version: 1.0",
+			ExpectedError:  commonlog.ErrHeader,
+			ExpectedReason: "header",
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			parse, err := commonlog.ParserFor(c.Format)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = parse(c.Line)
+			if !errors.Is(err, c.ExpectedError) {
+				t.Fatal("unexpected error", "expected", c.ExpectedError, "actual", err)
+			}
+			if reason := commonlog.Reason(err); reason != c.ExpectedReason {
+				t.Fatal("unexpected reason", "expected", c.ExpectedReason, "actual", reason)
+			}
+
+			// the reason is kept by the errors wrapping the parse error
+			wrapped := fmt.Errorf("line 1: %w", err)
+			if !errors.Is(wrapped, c.ExpectedError) || commonlog.Reason(wrapped) != c.ExpectedReason {
+				t.Fatal("unexpected wrapped error", "expected", c.ExpectedReason, "actual", commonlog.Reason(wrapped))
+			}
+
+			if c.Format != commonlog.CommonFormat {
+				return
+			}
+			var raw commonlog.RawEvent
+			err = commonlog.ParseBytes([]byte(c.Line), &raw)
+			if !errors.Is(err, c.ExpectedError) {
+				t.Fatal("unexpected raw error", "expected", c.ExpectedError, "actual", err)
+			}
+		})
+	}
+
+	if reason := commonlog.Reason(errors.New("cannot read")); reason != commonlog.OtherReason {
+		t.Fatal("unexpected reason", "expected", commonlog.OtherReason, "actual", reason)
+	}
+}
@@ -15,6 +15,18 @@ type Event struct {
 	Status  int
 	Bytes   int
 	Section string
+
+	// Source identifies the log of the event, the path of the file for the tailed logs
+	Source string
+	// Labels describe the transport of the event, such as the hostname of a syslog message
+	Labels map[string]string
+
+	// Duration is the time taken to serve the request when it is logged after the bytes
+	Duration    time.Duration
+	HasDuration bool
+
+	// Fields are the extra fields of the log format, such as the target status of a load balancer
+	Fields map[string]string
 }
 
 func (e Event) String() string {
@@ -0,0 +1,4 @@
+package commonlog
+
+// ParseLexer exposes the lexer to compare it with the parser of the byte slices
+var ParseLexer = parseLexer
@@ -0,0 +1,48 @@
+package commonlog
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Formats of the access logs
+const (
+	CommonFormat     = "common"     // common log format, optionally followed by the request time
+	ALBFormat        = "alb"        // AWS application load balancer
+	ELBFormat        = "elb"        // AWS classic load balancer
+	CloudFrontFormat = "cloudfront" // AWS CloudFront standard logs
+	GCPFormat        = "gcp"        // Google Cloud HTTP(S) load balancer logs exported as JSON
+	HAProxyFormat    = "haproxy"    // HAProxy default HTTP log format
+)
+
+// Parser parses a line of an access log into an event
+type Parser func(line string) (Event, error)
+
+var parsers = map[string]Parser{
+	CommonFormat:     Parse,
+	ALBFormat:        ParseALB,
+	ELBFormat:        ParseELB,
+	CloudFrontFormat: ParseCloudFront,
+	GCPFormat:        ParseGCP,
+	HAProxyFormat:    ParseHAProxy,
+}
+
+// ParserFor returns the parser of the format
+func ParserFor(format string) (Parser, error) {
+	parser, ok := parsers[format]
+	if !ok {
+		return nil, fmt.Errorf("unknown log format %q - expected one of %v", format, Formats())
+	}
+
+	return parser, nil
+}
+
+// Formats returns the names of the formats supported
+func Formats() []string {
+	formats := make([]string, 0, len(parsers))
+	for format := range parsers {
+		formats = append(formats, format)
+	}
+	sort.Strings(formats)
+	return formats
+}
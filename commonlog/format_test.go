@@ -0,0 +1,286 @@
+package commonlog_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ali.ghanem/http-log-monitoring/commonlog"
+)
+
+func TestParsers(t *testing.T) {
+	type testCase struct {
+		Format        string
+		Line          string
+		Expected      commonlog.Event
+		ExpectedError error
+	}
+
+	cases := map[string]testCase{
+		"alb": {
+			Format: commonlog.ALBFormat,
+			Line: `https 2018-07-02T22:23:00.186641Z app/my-loadbalancer/50dc6c495c0c9188 192.168.131.39:2817 10.0.0.1:80 0.086 0.048 0.037 200 200 0 57 ` +
+				`"GET https://www.example.com:443/api/users?page=2 HTTP/1.1" "curl/7.46.0" ECDHE-RSA-AES128-GCM-SHA256 TLSv1.2 ` +
+				`arn:aws:elasticloadbalancing:us-east-2:123456789012:targetgroup/my-targets/73e2d6bc24d8a067 "Root=1-58337281-1d84f3d73c47ec4e58577259" ` +
+				`"www.example.com" "arn:aws:acm:us-east-2:123456789012:certificate/12345678-1234-1234-1234-123456789012" 1 2018-07-02T22:22:48.364000Z ` +
+				`"authenticate,forward" "-" "-" "10.0.0.1:80" "200" "-" "-"`,
+			Expected: commonlog.Event{
+				Host:        "192.168.131.39",
+				RFC931:      "-",
+				User:        "-",
+				Date:        time.Date(2018, 7, 2, 22, 23, 0, 186641000, time.UTC),
+				Request:     "GET /api/users?page=2 HTTP/1.1",
+				Status:      200,
+				Bytes:       57,
+				Section:     "api",
+				Duration:    171 * time.Millisecond,
+				HasDuration: true,
+				Fields: map[string]string{
+					commonlog.TypeField:                   "https",
+					commonlog.LoadBalancerField:           "app/my-loadbalancer/50dc6c495c0c9188",
+					commonlog.TargetField:                 "10.0.0.1:80",
+					commonlog.RequestProcessingTimeField:  "0.086",
+					commonlog.TargetProcessingTimeField:   "0.048",
+					commonlog.ResponseProcessingTimeField: "0.037",
+					commonlog.TargetStatusField:           "200",
+					commonlog.UserAgentField:              "curl/7.46.0",
+					commonlog.TraceIDField:                "Root=1-58337281-1d84f3d73c47ec4e58577259",
+				},
+			},
+		},
+		"alb without target": {
+			Format: commonlog.ALBFormat,
+			Line: `http 2018-11-30T22:23:00.186641Z app/my-loadbalancer/50dc6c495c0c9188 192.168.131.39:2817 - -1 -1 -1 503 - 34 366 ` +
+				`"GET http://www.example.com:80/checkout HTTP/1.1" "curl/7.46.0" - - - "Root=1-58337364-23a8c76965a2ef7629b185e3" "-" "-" 0 ` +
+				`2018-11-30T22:22:48.364000Z "forward" "-" "-" "-" "-" "-" "-"`,
+			Expected: commonlog.Event{
+				Host:    "192.168.131.39",
+				RFC931:  "-",
+				User:    "-",
+				Date:    time.Date(2018, 11, 30, 22, 23, 0, 186641000, time.UTC),
+				Request: "GET /checkout HTTP/1.1",
+				Status:  503,
+				Bytes:   366,
+				Section: "checkout",
+				Fields: map[string]string{
+					commonlog.TypeField:                   "http",
+					commonlog.LoadBalancerField:           "app/my-loadbalancer/50dc6c495c0c9188",
+					commonlog.TargetField:                 "-",
+					commonlog.RequestProcessingTimeField:  "-1",
+					commonlog.TargetProcessingTimeField:   "-1",
+					commonlog.ResponseProcessingTimeField: "-1",
+					commonlog.TargetStatusField:           "-",
+					commonlog.UserAgentField:              "curl/7.46.0",
+					commonlog.TraceIDField:                "Root=1-58337364-23a8c76965a2ef7629b185e3",
+				},
+			},
+		},
+		"elb": {
+			Format: commonlog.ELBFormat,
+			Line: `2015-05-13T23:39:43.945958Z my-loadbalancer 192.168.131.39:2817 10.0.0.1:80 0.000073 0.001048 0.000057 200 200 0 29 ` +
+				`"GET http://www.example.com:80/index.html HTTP/1.1" "curl/7.38.0" - -`,
+			Expected: commonlog.Event{
+				Host:        "192.168.131.39",
+				RFC931:      "-",
+				User:        "-",
+				Date:        time.Date(2015, 5, 13, 23, 39, 43, 945958000, time.UTC),
+				Request:     "GET /index.html HTTP/1.1",
+				Status:      200,
+				Bytes:       29,
+				Section:     "index.html",
+				Duration:    1178 * time.Microsecond,
+				HasDuration: true,
+				Fields: map[string]string{
+					commonlog.LoadBalancerField:           "my-loadbalancer",
+					commonlog.TargetField:                 "10.0.0.1:80",
+					commonlog.RequestProcessingTimeField:  "0.000073",
+					commonlog.TargetProcessingTimeField:   "0.001048",
+					commonlog.ResponseProcessingTimeField: "0.000057",
+					commonlog.TargetStatusField:           "200",
+					commonlog.UserAgentField:              "curl/7.38.0",
+				},
+			},
+		},
+		"cloudfront": {
+			Format: commonlog.CloudFrontFormat,
+			Line: "2019-12-04\t21:02:31\tLAX1\t392\t192.0.2.100\tGET\td111111abcdef8.cloudfront.net\t/index.html\t200\t-\t" +
+				"Mozilla/5.0%20(Windows%20NT%2010.0;%20Win64;%20x64)\t-\t-\tHit\tSOX4xwn4XV6Q4rgb7XiVGOHms_BGlTAC4KyHmureZmBNrjGdRLiNIQ==\t" +
+				"d111111abcdef8.cloudfront.net\thttps\t23\t0.001\t-\tTLSv1.2\tECDHE-RSA-AES128-GCM-SHA256\tHit\tHTTP/2.0\t-\t-\t11040\t0.001\tHit\t" +
+				"text/html\t78\t-\t-",
+			Expected: commonlog.Event{
+				Host:        "192.0.2.100",
+				RFC931:      "-",
+				User:        "-",
+				Date:        time.Date(2019, 12, 4, 21, 2, 31, 0, time.UTC),
+				Request:     "GET /index.html HTTP/2.0",
+				Status:      200,
+				Bytes:       392,
+				Section:     "index.html",
+				Duration:    time.Millisecond,
+				HasDuration: true,
+				Fields: map[string]string{
+					commonlog.EdgeLocationField:           "LAX1",
+					commonlog.EdgeResultTypeField:         "Hit",
+					commonlog.EdgeResponseResultTypeField: "Hit",
+					commonlog.TimeToFirstByteField:        "0.001",
+					commonlog.UserAgentField:              "Mozilla/5.0%20(Windows%20NT%2010.0;%20Win64;%20x64)",
+				},
+			},
+		},
+		"cloudfront header": {
+			Format:        commonlog.CloudFrontFormat,
+			Line:          "#Version: 1.0",
+			ExpectedError: commonlog.ErrHeader,
+		},
+		"gcp": {
+			Format: commonlog.GCPFormat,
+			Line: `{"insertId":"1ufrqx5g2m4xk0","jsonPayload":{"@type":"type.googleapis.com/google.cloud.loadbalancing.type.LoadBalancerLogEntry",` +
+				`"statusDetails":"response_sent_by_backend"},"httpRequest":{"requestMethod":"GET","requestUrl":"https://example.com/static/app.js?v=3",` +
+				`"requestSize":"120","status":304,"responseSize":"254","userAgent":"curl/7.68.0","remoteIp":"203.0.113.10","serverIp":"10.128.0.5",` +
+				`"latency":"0.012345s","protocol":"HTTP/1.1","cacheLookup":true,"cacheHit":true},"resource":{"type":"http_load_balancer",` +
+				`"labels":{"backend_service_name":"web-backend","forwarding_rule_name":"web-rule","project_id":"my-project","zone":"global"}},` +
+				`"timestamp":"2020-02-20T10:25:32.123456Z","severity":"INFO","logName":"projects/my-project/logs/requests"}`,
+			Expected: commonlog.Event{
+				Host:        "203.0.113.10",
+				RFC931:      "-",
+				User:        "-",
+				Date:        time.Date(2020, 2, 20, 10, 25, 32, 123456000, time.UTC),
+				Request:     "GET /static/app.js?v=3 HTTP/1.1",
+				Status:      304,
+				Bytes:       254,
+				Section:     "static",
+				Duration:    12345 * time.Microsecond,
+				HasDuration: true,
+				Fields: map[string]string{
+					commonlog.StatusDetailsField:  "response_sent_by_backend",
+					commonlog.BackendServiceField: "web-backend",
+					commonlog.ServerIPField:       "10.128.0.5",
+					commonlog.CacheHitField:       "true",
+					commonlog.UserAgentField:      "curl/7.68.0",
+				},
+			},
+		},
+		"haproxy": {
+			Format: commonlog.HAProxyFormat,
+			Line: `Feb  6 12:14:14 localhost haproxy[14389]: 10.0.1.2:33317 [06/Feb/2009:12:14:14.655] http-in static/srv1 10/0/30/69/109 200 2750 ` +
+				`- - ---- 1/1/1/1/0 0/0 {1wt.eu} {} "GET /index.html HTTP/1.1"`,
+			Expected: commonlog.Event{
+				Host:        "10.0.1.2",
+				RFC931:      "-",
+				User:        "-",
+				Date:        time.Date(2009, 2, 6, 12, 14, 14, 655000000, time.Local),
+				Request:     "GET /index.html HTTP/1.1",
+				Status:      200,
+				Bytes:       2750,
+				Section:     "index.html",
+				Duration:    109 * time.Millisecond,
+				HasDuration: true,
+				Fields: map[string]string{
+					commonlog.FrontendField:            "http-in",
+					commonlog.BackendField:             "static",
+					commonlog.ServerField:              "srv1",
+					commonlog.RequestTimeField:         "10",
+					commonlog.QueueTimeField:           "0",
+					commonlog.ConnectTimeField:         "30",
+					commonlog.ResponseTimeField:        "69",
+					commonlog.TotalTimeField:           "109",
+					commonlog.TerminationStateField:    "----",
+					commonlog.ActiveConnectionsField:   "1",
+					commonlog.FrontendConnectionsField: "1",
+					commonlog.BackendConnectionsField:  "1",
+					commonlog.ServerConnectionsField:   "1",
+					commonlog.RetriesField:             "0",
+					commonlog.ServerQueueField:         "0",
+					commonlog.BackendQueueField:        "0",
+				},
+			},
+		},
+		"haproxy server timeout": {
+			Format: commonlog.HAProxyFormat,
+			Line: `10.0.1.2:33318 [06/Feb/2009:12:14:15.002] http-in dynamic/srv2 0/0/1/-1/+30001 504 194 ` +
+				`- - sH-- 2/2/1/1/0 0/0 "POST /api/orders HTTP/1.1"`,
+			Expected: commonlog.Event{
+				Host:        "10.0.1.2",
+				RFC931:      "-",
+				User:        "-",
+				Date:        time.Date(2009, 2, 6, 12, 14, 15, 2000000, time.Local),
+				Request:     "POST /api/orders HTTP/1.1",
+				Status:      504,
+				Bytes:       194,
+				Section:     "api",
+				Duration:    30001 * time.Millisecond,
+				HasDuration: true,
+				Fields: map[string]string{
+					commonlog.FrontendField:            "http-in",
+					commonlog.BackendField:             "dynamic",
+					commonlog.ServerField:              "srv2",
+					commonlog.RequestTimeField:         "0",
+					commonlog.QueueTimeField:           "0",
+					commonlog.ConnectTimeField:         "1",
+					commonlog.ResponseTimeField:        "-1",
+					commonlog.TotalTimeField:           "+30001",
+					commonlog.TerminationStateField:    "sH--",
+					commonlog.ActiveConnectionsField:   "2",
+					commonlog.FrontendConnectionsField: "2",
+					commonlog.BackendConnectionsField:  "1",
+					commonlog.ServerConnectionsField:   "1",
+					commonlog.RetriesField:             "0",
+					commonlog.ServerQueueField:         "0",
+					commonlog.BackendQueueField:        "0",
+				},
+			},
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			parse, err := commonlog.ParserFor(c.Format)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			actual, err := parse(c.Line)
+			if !errors.Is(err, c.ExpectedError) {
+				t.Fatal("unexpected error", "expected", c.ExpectedError, "actual", err)
+			}
+			if c.ExpectedError != nil {
+				return
+			}
+
+			if !actual.Date.Equal(c.Expected.Date) {
+				t.Fatal("unexpected date", "expected", c.Expected.Date, "actual", actual.Date)
+			}
+			actual.Date = c.Expected.Date
+			if !reflect.DeepEqual(c.Expected, actual) {
+				t.Fatal("unexpected event", "expected", c.Expected, "actual", actual)
+			}
+		})
+	}
+}
+
+func TestParseHAProxy_LocalTime(t *testing.T) {
+	local := time.Local
+	time.Local = time.FixedZone("CET", 3600)
+	defer func() {
+		time.Local = local
+	}()
+
+	event, err := commonlog.ParseHAProxy(`10.0.1.2:33317 [06/Feb/2009:12:14:14.655] http-in static/srv1 10/0/30/69/109 200 2750 ` +
+		`- - ---- 1/1/1/1/0 0/0 "GET /index.html HTTP/1.1"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := time.Date(2009, 2, 6, 11, 14, 14, 655000000, time.UTC)
+	if !event.Date.Equal(expected) {
+		t.Fatal("unexpected date", "expected", expected, "actual", event.Date.UTC())
+	}
+}
+
+func TestParserFor_Unknown(t *testing.T) {
+	_, err := commonlog.ParserFor("apache")
+	if err == nil {
+		t.Fatal("unexpected parser of an unknown format")
+	}
+}
@@ -0,0 +1,98 @@
+package commonlog
+
+import (
+	"encoding/json"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Extra fields of the Google Cloud load balancer logs
+const (
+	StatusDetailsField  = "status_details"
+	BackendServiceField = "backend_service_name"
+	ServerIPField       = "server_ip"
+	CacheHitField       = "cache_hit"
+)
+
+// gcpLogEntry is a log entry of a Google Cloud HTTP(S) load balancer, as exported by Cloud Logging
+type gcpLogEntry struct {
+	Timestamp   time.Time `json:"timestamp"`
+	HTTPRequest *struct {
+		RequestMethod string      `json:"requestMethod"`
+		RequestURL    string      `json:"requestUrl"`
+		Status        int         `json:"status"`
+		ResponseSize  json.Number `json:"responseSize"`
+		UserAgent     string      `json:"userAgent"`
+		RemoteIP      string      `json:"remoteIp"`
+		ServerIP      string      `json:"serverIp"`
+		Latency       string      `json:"latency"`
+		Protocol      string      `json:"protocol"`
+		CacheHit      bool        `json:"cacheHit"`
+	} `json:"httpRequest"`
+	JSONPayload struct {
+		StatusDetails string `json:"statusDetails"`
+	} `json:"jsonPayload"`
+	Resource struct {
+		Labels map[string]string `json:"labels"`
+	} `json:"resource"`
+}
+
+// ParseGCP parses a Google Cloud HTTP(S) load balancer log entry written as a JSON object
+func ParseGCP(line string) (event Event, err error) {
+	if len(line) == 0 {
+		return event, ErrEmptyLine
+	}
+
+	var entry gcpLogEntry
+	err = json.Unmarshal([]byte(line), &entry)
+	if err != nil {
+		return event, parseError(ErrMalformed, "invalid log entry: %w", err)
+	}
+	if entry.HTTPRequest == nil {
+		return event, parseError(ErrMissingField, "http request not found")
+	}
+	request := entry.HTTPRequest
+
+	event.Date = entry.Timestamp
+	event.Host = request.RemoteIP
+	event.RFC931 = "-"
+	event.User = "-"
+	event.Status = request.Status
+
+	// the sizes are 64 bits integers written as strings
+	if len(request.ResponseSize) > 0 {
+		bytes, err := strconv.Atoi(request.ResponseSize.String())
+		if err != nil {
+			return event, parseError(ErrBadBytes, "invalid bytes number: %w", err)
+		}
+		event.Bytes = bytes
+	}
+
+	target, err := url.Parse(request.RequestURL)
+	if err != nil {
+		return event, parseError(ErrBadRequest, "invalid request url: %w", err)
+	}
+	event.Request = request.RequestMethod + " " + target.RequestURI()
+	if len(request.Protocol) > 0 {
+		event.Request += " " + request.Protocol
+	}
+	event.Section, err = ParseSection(target.EscapedPath() + " ")
+	if err != nil {
+		return event, err
+	}
+
+	if latency, err := time.ParseDuration(request.Latency); err == nil {
+		event.Duration = latency
+		event.HasDuration = true
+	}
+
+	event.Fields = map[string]string{
+		StatusDetailsField:  entry.JSONPayload.StatusDetails,
+		BackendServiceField: entry.Resource.Labels[BackendServiceField],
+		ServerIPField:       request.ServerIP,
+		CacheHitField:       strconv.FormatBool(request.CacheHit),
+		UserAgentField:      request.UserAgent,
+	}
+	return event, nil
+}
@@ -0,0 +1,140 @@
+package commonlog
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Extra fields of the HAProxy logs
+const (
+	FrontendField            = "frontend"
+	BackendField             = "backend"
+	ServerField              = "server"
+	RequestTimeField         = "tq" // time to receive the request, TR since HAProxy 1.7
+	QueueTimeField           = "tw"
+	ConnectTimeField         = "tc"
+	ResponseTimeField        = "tr"
+	TotalTimeField           = "tt" // total time of the session, Ta since HAProxy 1.7
+	TerminationStateField    = "termination_state"
+	ActiveConnectionsField   = "actconn"
+	FrontendConnectionsField = "feconn"
+	BackendConnectionsField  = "beconn"
+	ServerConnectionsField   = "srv_conn"
+	RetriesField             = "retries"
+	ServerQueueField         = "srv_queue"
+	BackendQueueField        = "backend_queue"
+)
+
+// haproxyTimeLayout is the layout of the accept date of the HAProxy logs
+const haproxyTimeLayout = "02/Jan/2006:15:04:05.000"
+
+// ParseHAProxy parses a line of the HAProxy default HTTP log format (option httplog):
+// client_ip:port [accept_date] frontend backend/server Tq/Tw/Tc/Tr/Tt status bytes_read
+// request_cookie response_cookie termination_state actconn/feconn/beconn/srv_conn/retries
+// srv_queue/backend_queue {request_headers} {response_headers} "request"
+// The syslog header written before the client is ignored. The accept date is in the time zone of the proxy,
+// it is read as UTC.
+func ParseHAProxy(line string) (event Event, err error) {
+	if len(line) == 0 {
+		return event, ErrEmptyLine
+	}
+
+	// client, the last field before the accept date
+	start := strings.Index(line, " [")
+	if start < 0 {
+		return event, parseError(ErrMissingField, "accept date not found")
+	}
+	client := line[strings.LastIndexByte(line[:start], ' ')+1 : start]
+	event.Host = client
+	if host, _, err := net.SplitHostPort(client); err == nil {
+		event.Host = host
+	}
+	event.RFC931 = "-"
+	event.User = "-"
+
+	end := strings.IndexByte(line[start:], ']')
+	if end < 0 {
+		return event, parseError(ErrMissingField, "accept date not terminated")
+	}
+	// haproxy logs the local time without its offset
+	event.Date, err = time.ParseInLocation(haproxyTimeLayout, line[start+2:start+end], time.Local)
+	if err != nil {
+		return event, parseError(ErrBadDate, "invalid date format: %w", err)
+	}
+
+	rest := strings.TrimPrefix(line[start+end+1:], " ")
+	fields := strings.SplitN(rest, " ", 11)
+	if len(fields) < 11 {
+		return event, parseError(ErrMissingField, "missing fields: %v fields - expected at least 11", len(fields))
+	}
+
+	event.Fields = map[string]string{
+		FrontendField:         fields[0],
+		TerminationStateField: fields[7],
+	}
+
+	backend := strings.SplitN(fields[1], "/", 2)
+	if len(backend) != 2 {
+		return event, parseError(ErrMalformed, "invalid backend and server: %s", fields[1])
+	}
+	event.Fields[BackendField] = backend[0]
+	event.Fields[ServerField] = backend[1]
+
+	err = splitInto(event.Fields, fields[2], RequestTimeField, QueueTimeField, ConnectTimeField, ResponseTimeField, TotalTimeField)
+	if err != nil {
+		return event, parseError(ErrMalformed, "invalid timers: %w", err)
+	}
+	// the total time is prefixed by + when the log is written before the end of the session
+	if total, err := strconv.Atoi(strings.TrimPrefix(event.Fields[TotalTimeField], "+")); err == nil && total >= 0 {
+		event.Duration = time.Duration(total) * time.Millisecond
+		event.HasDuration = true
+	}
+
+	event.Status, err = strconv.Atoi(fields[3])
+	if err != nil {
+		return event, parseError(ErrBadStatus, "invalid status format: %w", err)
+	}
+	event.Bytes, err = strconv.Atoi(fields[4])
+	if err != nil {
+		return event, parseError(ErrBadBytes, "invalid bytes number: %w", err)
+	}
+
+	err = splitInto(event.Fields, fields[8], ActiveConnectionsField, FrontendConnectionsField, BackendConnectionsField, ServerConnectionsField, RetriesField)
+	if err != nil {
+		return event, parseError(ErrMalformed, "invalid connection counts: %w", err)
+	}
+	err = splitInto(event.Fields, fields[9], ServerQueueField, BackendQueueField)
+	if err != nil {
+		return event, parseError(ErrMalformed, "invalid queues: %w", err)
+	}
+
+	// the captured headers are optional, the request is the last field
+	request := fields[10]
+	quote := strings.IndexByte(request, '"')
+	if quote < 0 || !strings.HasSuffix(request, `"`) || len(request)-quote < 2 {
+		return event, parseError(ErrMissingField, "request not found")
+	}
+	event.Request = request[quote+1 : len(request)-1]
+	event.Section, err = ParseSection(event.Request)
+	if err != nil {
+		return event, err
+	}
+
+	return event, nil
+}
+
+// splitInto sets the fields with the values separated by slashes
+func splitInto(fields map[string]string, value string, names ...string) error {
+	values := strings.Split(value, "/")
+	if len(values) != len(names) {
+		return fmt.Errorf("%s - expected %v values", value, len(names))
+	}
+
+	for i, name := range names {
+		fields[name] = values[i]
+	}
+	return nil
+}
@@ -1,9 +1,7 @@
 package commonlog
 
 import (
-	"errors"
 	"fmt"
-	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -11,19 +9,44 @@ import (
 
 const timeLayout = "02/Jan/2006:15:04:05 -0700"
 
-// Regex to extract the section name
-var sectionRegex = regexp.MustCompile(`\/([0-9a-zA-Z+.-]+)[\/&| ]`)
-
 // Lexer to read a common log format
 type lexer struct {
 	position int
 	line     string
 }
 
-// Parse parses the line and returns a log Event
-func Parse(line string) (event Event, err error) {
+// Parse parses the line and returns a log Event.
+// The line is read by ParseBytes, the lexer reads the lines it rejects to accept them or describe their error.
+func Parse(line string) (Event, error) {
+	buffer := []byte(line)
+	var raw RawEvent
+	if ParseBytes(buffer, &raw) != nil {
+		return parseLexer(line)
+	}
+
+	// the fields are the same parts of the line, without copying them
+	field := func(value []byte) string {
+		start := cap(buffer) - cap(value)
+		return line[start : start+len(value)]
+	}
+	return Event{
+		Host:        field(raw.Host),
+		RFC931:      field(raw.RFC931),
+		User:        field(raw.User),
+		Date:        raw.Date,
+		Request:     field(raw.Request),
+		Status:      raw.Status,
+		Bytes:       raw.Bytes,
+		Section:     field(raw.Section),
+		Duration:    raw.Duration,
+		HasDuration: raw.HasDuration,
+	}, nil
+}
+
+// parseLexer parses the line field by field and describes the errors
+func parseLexer(line string) (event Event, err error) {
 	if len(line) == 0 {
-		return event, errors.New("empty log line")
+		return event, ErrEmptyLine
 	}
 
 	l := lexer{
@@ -33,89 +56,120 @@ func Parse(line string) (event Event, err error) {
 	// host
 	value, err := l.nextField(' ')
 	if err != nil {
-		return event, fmt.Errorf("reading host: %w - event: %s", err, event)
+		return event, parseError(ErrMissingField, "reading host: %w - event: %s", err, event)
 	}
 	event.Host = value
 
 	// RFC931
 	value, err = l.nextField(' ')
 	if err != nil {
-		return event, fmt.Errorf("reading rfc931: %w - event: %s", err, event)
+		return event, parseError(ErrMissingField, "reading rfc931: %w - event: %s", err, event)
 	}
 	event.RFC931 = value
 
 	// User
 	value, err = l.nextField(' ')
 	if err != nil {
-		return event, fmt.Errorf("reading user: %w - event: %s", err, event)
+		return event, parseError(ErrMissingField, "reading user: %w - event: %s", err, event)
 	}
 	event.User = value
 
 	// Date
 	err = l.except('[')
 	if err != nil {
-		return event, fmt.Errorf("reading date: %w - event: %s", err, event)
+		return event, parseError(ErrMissingField, "reading date: %w - event: %s", err, event)
 	}
 
 	value, err = l.nextField(']')
 	if err != nil {
-		return event, fmt.Errorf("reading date: %w - event: %s", err, event)
+		return event, parseError(ErrMissingField, "reading date: %w - event: %s", err, event)
 	}
 	event.Date, err = time.Parse(timeLayout, value)
 	if err != nil {
-		return event, fmt.Errorf("invalid date format: %w", err)
+		return event, parseError(ErrBadDate, "invalid date format: %w", err)
 	}
 
 	err = l.except(' ')
 	if err != nil {
-		return event, fmt.Errorf("reading request: %w - event: %s", err, event)
+		return event, parseError(ErrMissingField, "reading request: %w - event: %s", err, event)
 	}
 
 	// Request
 	err = l.except('"')
 	if err != nil {
-		return event, fmt.Errorf("reading request: %w - event: %s", err, event)
+		return event, parseError(ErrMissingField, "reading request: %w - event: %s", err, event)
 	}
 
 	value, err = l.nextField('"')
 	if err != nil {
-		return event, fmt.Errorf("reading request: %w - event: %s", err, event)
+		return event, parseError(ErrMissingField, "reading request: %w - event: %s", err, event)
 	}
 	event.Request = value
-	section := sectionRegex.FindString(value)
-	if len(section) == 0 {
-		return event, fmt.Errorf("section not found: request %s", event.Request)
+	event.Section, err = ParseSection(value)
+	if err != nil {
+		return event, err
 	}
-	event.Section = section[1 : len(section)-1] // remove the first / and remove the last character which can a / or a space
 
 	err = l.except(' ')
 	if err != nil {
-		return event, fmt.Errorf("reading status: %w - event: %s", err, event)
+		return event, parseError(ErrMissingField, "reading status: %w - event: %s", err, event)
 	}
 
 	// Status code
 	value, err = l.nextField(' ')
 	if err != nil {
-		return event, fmt.Errorf("reading status: %w - event: %s", err, event)
+		return event, parseError(ErrMissingField, "reading status: %w - event: %s", err, event)
 	}
 	event.Status, err = strconv.Atoi(value)
 	if err != nil {
-		return event, fmt.Errorf("invalid status format: %w", err)
+		return event, parseError(ErrBadStatus, "invalid status format: %w", err)
 	}
 
 	// Bytes
 	value, err = l.nextField(' ')
-	if err != nil {
-		// last field
+	last := err != nil
+	if last {
 		value = l.line[l.position:]
 	}
 	event.Bytes, err = strconv.Atoi(value)
 	if err != nil {
-		return event, fmt.Errorf("invalid bytes number: %w", err)
+		return event, parseError(ErrBadBytes, "invalid bytes number: %w", err)
+	}
+	if last {
+		return event, nil
+	}
+
+	// Request time in seconds as the last field, like the nginx $request_time,
+	// other extra fields as the referer or the user agent are ignored
+	extra := strings.TrimSpace(l.line[l.position:])
+	value = extra[strings.LastIndexByte(extra, ' ')+1:]
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds >= 0 && strings.Contains(value, ".") {
+		event.Duration = time.Duration(seconds * float64(time.Second))
+		event.HasDuration = true
 	}
 	return event, nil
 }
 
+// ParseSection returns the section of the request, the first segment of its path
+func ParseSection(request string) (string, error) {
+	for i := 0; i < len(request); i++ {
+		if request[i] != '/' {
+			continue
+		}
+
+		end := i + 1
+		for end < len(request) && isSectionByte(request[end]) {
+			end++
+		}
+		// the section is followed by a / or a space
+		if end > i+1 && end < len(request) && isSectionEnd(request[end]) {
+			return request[i+1 : end], nil
+		}
+	}
+
+	return "", parseError(ErrNoSection, "section not found: request %s", request)
+}
+
 func (l *lexer) nextField(separator byte) (string, error) {
 	var buffer strings.Builder
 	for i := l.position; i < len(l.line); i++ {
@@ -130,7 +184,7 @@ func (l *lexer) nextField(separator byte) (string, error) {
 }
 
 func (l *lexer) except(rune byte) error {
-	if l.line[l.position] == rune {
+	if l.position < len(l.line) && l.line[l.position] == rune {
 		l.position++
 		return nil
 	}
@@ -3,6 +3,7 @@ package commonlog_test
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/ali.ghanem/http-log-monitoring/commonlog"
 )
@@ -19,6 +20,24 @@ func TestLogLexer_Parse(t *testing.T) {
 		t.Log("event", event)
 	})
 
+	t.Run("request time", func(t *testing.T) {
+		event, err := commonlog.Parse(`66.137.220.245 - - [10/Feb/2020:17:35:21 +0100] "GET /api/users HTTP/1.1" 200 512 "-" "curl/7.68.0" 0.250`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !event.HasDuration || event.Duration != 250*time.Millisecond {
+			t.Fatal("unexpected duration", "expected", 250*time.Millisecond, "actual", event.Duration)
+		}
+
+		event, err = commonlog.Parse(`66.137.220.245 - - [10/Feb/2020:17:35:21 +0100] "GET /api/users HTTP/1.1" 200 512 "-" "curl/7.68.0"`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if event.HasDuration {
+			t.Fatal("unexpected duration", "actual", event.Duration)
+		}
+	})
+
 	type testCase struct {
 		Line          string
 		ExpectedError string
@@ -63,7 +82,7 @@ func TestLogLexer_Parse(t *testing.T) {
 
 func BenchmarkLogLexer_Parse(b *testing.B) {
 	for n := 0; n < b.N; n++ {
-		_, err := commonlog.Parse(fmt.Sprintf(`66.%v.220.245 - - [21/Feb/2020:17:35:21 +0100] "POST /technologies/e-enable/collaborative/bandwidth HTTP/1.0" 200 19072`, n))
+		_, err := commonlog.ParseLexer(fmt.Sprintf(`66.%v.220.245 - - [21/Feb/2020:17:35:21 +0100] "POST /technologies/e-enable/collaborative/bandwidth HTTP/1.0" 200 19072`, n))
 		if err != nil {
 			b.Fatal(err)
 		}
@@ -0,0 +1,159 @@
+package commonlog
+
+import (
+	"bytes"
+	"strconv"
+	"time"
+)
+
+// RawEvent is a log entry in the common format whose fields are sub-slices of the line,
+// they are only valid until the line is modified
+type RawEvent struct {
+	Host    []byte
+	RFC931  []byte
+	User    []byte
+	Date    time.Time
+	Request []byte
+	Status  int
+	Bytes   int
+	Section []byte
+
+	Duration    time.Duration
+	HasDuration bool
+}
+
+// ParseBytes parses a line of the common format into the event without allocating.
+// It only reads the decimal numbers, Parse also accepts their signs and describes the errors of the lines rejected.
+// The error is the reason of the rejection, such as ErrBadDate.
+func ParseBytes(line []byte, event *RawEvent) error {
+	*event = RawEvent{}
+	if len(line) == 0 {
+		return ErrEmptyLine
+	}
+	rest := line
+
+	var ok bool
+	if event.Host, rest, ok = cut(rest, ' '); !ok {
+		return ErrMissingField
+	}
+	if event.RFC931, rest, ok = cut(rest, ' '); !ok {
+		return ErrMissingField
+	}
+	if event.User, rest, ok = cut(rest, ' '); !ok {
+		return ErrMissingField
+	}
+
+	// date
+	if len(rest) == 0 || rest[0] != '[' {
+		return ErrMissingField
+	}
+	var value []byte
+	if value, rest, ok = cut(rest[1:], ']'); !ok {
+		return ErrMissingField
+	}
+	var err error
+	if event.Date, err = decodeDate(value); err != nil {
+		return err
+	}
+
+	// request
+	if len(rest) < 2 || rest[0] != ' ' || rest[1] != '"' {
+		return ErrMissingField
+	}
+	if event.Request, rest, ok = cut(rest[2:], '"'); !ok {
+		return ErrMissingField
+	}
+	if event.Section = sectionBytes(event.Request); event.Section == nil {
+		return ErrNoSection
+	}
+
+	// status
+	if len(rest) == 0 || rest[0] != ' ' {
+		return ErrMissingField
+	}
+	if value, rest, ok = cut(rest[1:], ' '); !ok {
+		return ErrMissingField
+	}
+	if event.Status, ok = digits(value); !ok {
+		return ErrBadStatus
+	}
+
+	// bytes, the last field or followed by the extra fields
+	value, rest, ok = cut(rest, ' ')
+	if !ok {
+		value, rest = rest, nil
+	}
+	if event.Bytes, ok = digits(value); !ok {
+		return ErrBadBytes
+	}
+
+	event.Duration, event.HasDuration = requestTime(rest)
+	return nil
+}
+
+// cut returns the bytes before the first separator and the ones after it
+func cut(value []byte, separator byte) ([]byte, []byte, bool) {
+	i := bytes.IndexByte(value, separator)
+	if i < 0 {
+		return nil, value, false
+	}
+	return value[:i], value[i+1:], true
+}
+
+// requestTime reads the request time in seconds logged as the last of the extra fields, like the nginx $request_time
+func requestTime(extra []byte) (time.Duration, bool) {
+	extra = bytes.TrimSpace(extra)
+	value := extra[bytes.LastIndexByte(extra, ' ')+1:]
+	dot := bytes.IndexByte(value, '.')
+	if dot < 0 {
+		return 0, false
+	}
+
+	// the decimal seconds are read without allocating, the other notations are left to strconv
+	seconds, ok1 := digits(value[:dot])
+	if dot == 0 {
+		ok1 = true
+	}
+	fraction := value[dot+1:]
+	nanoseconds, ok2 := digits(fraction)
+	if ok1 && (ok2 || len(fraction) == 0) && len(fraction) <= 9 && (dot > 0 || len(fraction) > 0) {
+		for i := len(fraction); i < 9; i++ {
+			nanoseconds *= 10
+		}
+		return time.Duration(seconds)*time.Second + time.Duration(nanoseconds), true
+	}
+
+	float, err := strconv.ParseFloat(string(value), 64)
+	if err != nil || float < 0 {
+		return 0, false
+	}
+	return time.Duration(float * float64(time.Second)), true
+}
+
+// sectionBytes returns the section of the request, nil when it has none
+func sectionBytes(request []byte) []byte {
+	for i := 0; i < len(request); i++ {
+		if request[i] != '/' {
+			continue
+		}
+
+		end := i + 1
+		for end < len(request) && isSectionByte(request[end]) {
+			end++
+		}
+		if end > i+1 && end < len(request) && isSectionEnd(request[end]) {
+			return request[i+1 : end]
+		}
+	}
+	return nil
+}
+
+// isSectionByte returns whether the byte can be part of a section
+func isSectionByte(c byte) bool {
+	return ('0' <= c && c <= '9') || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || c == '+' || c == '.' || c == '-'
+}
+
+// isSectionEnd returns whether the byte ends a section
+func isSectionEnd(c byte) bool {
+	return c == '/' || c == '&' || c == '|' || c == ' '
+}
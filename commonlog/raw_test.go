@@ -0,0 +1,191 @@
+package commonlog_test
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/ali.ghanem/http-log-monitoring/commonlog"
+)
+
+func TestParseBytes(t *testing.T) {
+	type testCase struct {
+		Line          string
+		ExpectedError bool
+	}
+
+	cases := map[string]testCase{
+		"common format": {
+			Line: `66.137.220.245 - - [10/Feb/2020:17:35:21 +0100] "POST /technologies/e-enable/collaborative/bandwidth HTTP/1.0" 200 19072`,
+		},
+		"request time": {
+			Line: `66.137.220.245 - frank [10/Feb/2020:17:35:21 -0730] "GET /api/users HTTP/1.1" 200 512 "-" "curl/7.68.0" 0.250`,
+		},
+		"request time without integer part": {
+			Line: `66.137.220.245 - - [29/Feb/2020:00:00:00 +0000] "GET /api HTTP/1.1" 200 512 .5`,
+		},
+		"extra fields without request time": {
+			Line: `66.137.220.245 - - [10/feb/2020:17:35:21 +0100] "GET /api/users HTTP/1.1" 200 512 "-" "curl/7.68.0"`,
+		},
+		"empty line": {
+			Line:          "",
+			ExpectedError: true,
+		},
+		"invalid month": {
+			Line:          `66.137.220.245 rfc user [10/Inv/2020:17:35:21 +0100] "GET /api HTTP/1.1" 200 512`,
+			ExpectedError: true,
+		},
+		"day out of range": {
+			Line:          `66.137.220.245 rfc user [29/Feb/2019:17:35:21 +0100] "GET /api HTTP/1.1" 200 512`,
+			ExpectedError: true,
+		},
+		"missing date": {
+			Line:          `66.137.220.245 rfc user[10/Feb/2020:17:35:21 +0100]`,
+			ExpectedError: true,
+		},
+		"line ending after the date": {
+			Line:          `66.137.220.245 - - [10/Feb/2020:17:35:21 +0100]`,
+			ExpectedError: true,
+		},
+		"no section": {
+			Line:          `66.137.220.245 - - [10/Feb/2020:17:35:21 +0100] "GET / HTTP/1.1" 200 512`,
+			ExpectedError: true,
+		},
+		"invalid status": {
+			Line:          `66.137.220.245 - - [10/Feb/2020:17:35:21 +0100] "GET /api HTTP/1.1" XXX 512`,
+			ExpectedError: true,
+		},
+		"invalid bytes number": {
+			Line:          `66.137.220.245 - - [10/Feb/2020:17:35:21 +0100] "GET /api HTTP/1.1" 200 UUUUU`,
+			ExpectedError: true,
+		},
+		"status overflow": {
+			Line:          `66.137.220.245 - - [10/Feb/2020:17:35:21 +0100] "GET /api HTTP/1.1" 99999999999999999999 512`,
+			ExpectedError: true,
+		},
+		"bytes number overflow": {
+			Line:          `66.137.220.245 - - [10/Feb/2020:17:35:21 +0100] "GET /api HTTP/1.1" 200 99999999999999999999999`,
+			ExpectedError: true,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			var raw commonlog.RawEvent
+			err := commonlog.ParseBytes([]byte(c.Line), &raw)
+			if (err != nil) != c.ExpectedError {
+				t.Fatal("unexpected error", "expected", c.ExpectedError, "actual", err)
+			}
+
+			// the lexer reads the same events
+			expected, lexerErr := commonlog.ParseLexer(c.Line)
+			if (lexerErr != nil) != c.ExpectedError {
+				t.Fatal("unexpected lexer error", "expected", c.ExpectedError, "actual", lexerErr)
+			}
+			if c.ExpectedError {
+				return
+			}
+
+			actual := commonlog.Event{
+				Host:        string(raw.Host),
+				RFC931:      string(raw.RFC931),
+				User:        string(raw.User),
+				Date:        raw.Date,
+				Request:     string(raw.Request),
+				Status:      raw.Status,
+				Bytes:       raw.Bytes,
+				Section:     string(raw.Section),
+				Duration:    raw.Duration,
+				HasDuration: raw.HasDuration,
+			}
+			if !reflect.DeepEqual(expected, actual) {
+				t.Fatal("unexpected event", "expected", expected, "actual", actual)
+			}
+
+			event, err := commonlog.Parse(c.Line)
+			if err != nil || !reflect.DeepEqual(expected, event) {
+				t.Fatal("unexpected parsed event", "expected", expected, "actual", event, err)
+			}
+		})
+	}
+}
+
+func TestParseBytes_Allocations(t *testing.T) {
+	line := []byte(`66.137.220.245 - - [10/Feb/2020:17:35:21 +0100] "GET /api/users HTTP/1.1" 200 512 "-" "curl/7.68.0" 0.250`)
+
+	var raw commonlog.RawEvent
+	allocations := testing.AllocsPerRun(100, func() {
+		err := commonlog.ParseBytes(line, &raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+	if allocations != 0 {
+		t.Fatal("unexpected allocations", "expected", 0, "actual", allocations)
+	}
+}
+
+func TestParseSection(t *testing.T) {
+	cases := map[string]string{
+		"GET /api/users HTTP/1.1":        "api",
+		"GET /report HTTP/1.0":           "report",
+		"GET /?q=1 HTTP/1.1":             "",
+		"GET /v1.2-beta&x HTTP/1.1":      "v1.2-beta",
+		"GET /_private/a HTTP/1.1":       "a",
+		"GET /_private /public HTTP/1.1": "public",
+	}
+
+	for request, expected := range cases {
+		t.Run(request, func(t *testing.T) {
+			actual, err := commonlog.ParseSection(request)
+			if (err != nil) != (len(expected) == 0) || expected != actual {
+				t.Fatal("unexpected section", "expected", expected, "actual", actual, err)
+			}
+		})
+	}
+}
+
+// benchmarkLines returns lines of the common format with distinct hosts
+func benchmarkLines() [][]byte {
+	lines := make([][]byte, 1024)
+	for i := range lines {
+		lines[i] = []byte(fmt.Sprintf(`66.%v.220.245 - - [21/Feb/2020:17:35:21 +0100] "POST /technologies/e-enable/collaborative/bandwidth HTTP/1.0" 200 19072`, i))
+	}
+	return lines
+}
+
+func BenchmarkParsers(b *testing.B) {
+	lines := benchmarkLines()
+	texts := make([]string, len(lines))
+	for i, line := range lines {
+		texts[i] = string(line)
+	}
+
+	b.Run("lexer", func(b *testing.B) {
+		b.ReportAllocs()
+		for n := 0; n < b.N; n++ {
+			if _, err := commonlog.ParseLexer(texts[n%len(texts)]); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("Parse", func(b *testing.B) {
+		b.ReportAllocs()
+		for n := 0; n < b.N; n++ {
+			if _, err := commonlog.Parse(texts[n%len(texts)]); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("ParseBytes", func(b *testing.B) {
+		b.ReportAllocs()
+		var raw commonlog.RawEvent
+		for n := 0; n < b.N; n++ {
+			if err := commonlog.ParseBytes(lines[n%len(lines)], &raw); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
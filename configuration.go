@@ -1,14 +1,34 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"strconv"
+	"strings"
 	"time"
+
+	"github.com/ali.ghanem/http-log-monitoring/commonlog"
 )
 
 type Configuration struct {
-	LogToMonitor string // File path of the log to monitor
+	Mode        string  // FollowMode to tail the logs, BatchMode or ReplayMode to analyze them to their end
+	ReplaySpeed float64 // Multiplier of the pace of the events in replay mode, 0 to replay them as fast as possible
+
+	LogsToMonitor     []string      // File paths and glob patterns of the logs to monitor, StdinPath for the standard input
+	DiscoveryInterval time.Duration // Interval to evaluate again the glob patterns to monitor the new files
+	LogFormat         string        // Format of the access logs, commonlog.CommonFormat by default
+	LogEnvelope       string        // Envelope of the container runtime wrapping the lines, NoEnvelope by default
+
+	SyslogAddresses []string // Addresses receiving the logs by syslog, such as "udp://:514", in follow mode only
+
+	Parsing ParseConfig // Workers parsing the lines in follow mode
+
+	DeadLetter DeadLetterConfig // File of the lines rejected, disabled when the path is empty
+
+	CheckpointFile     string        // File path of the positions reached in the logs, optional
+	CheckpointInterval time.Duration // Interval to save the positions reached in the logs
 
 	StatsDisplayInterval  time.Duration // Time to wait before displaying the statistics of the consumption
 	StatsTopSectionsCount int           // The number of sections with maximum hits
@@ -16,18 +36,112 @@ type Configuration struct {
 	TrafficLoadCheckInterval time.Duration // Check alert interval
 	TrafficLoadPeriod        time.Duration // Period to verify for the traffic load
 	TrafficThreshold         int64         // Traffic threshold in number of requests / second
+	TrafficLowThreshold      int64         // Alert when the traffic falls below this number of requests / second, 0 to disable
+
+	SectionTrafficThresholds map[string]int64 // Traffic thresholds in number of requests / second by section
+	HostTrafficThresholds    map[string]int64 // Traffic thresholds in number of requests / second by client host
+
+	TerminationStateThresholds map[string]int64 // Alert thresholds in number of proxy sessions during the load period by termination state
+
+	SLOObjectives map[string]float64 // Availability objectives by section, as ratios of requests without server error
+	SLOWindow     time.Duration      // Rolling window of the error budgets
+
+	ApdexTargets    map[string]time.Duration // Apdex target response time by section
+	ApdexThresholds map[string]float64       // Alert when the apdex score of a section falls below its threshold
+
+	NoDataTimeout time.Duration // Alert when no line is read from the log during this time, 0 to disable
+
+	AlertPendingDuration time.Duration // Time a rule condition must be met before its alert fires, 0 to fire immediately
+	AlertRepeatInterval  time.Duration // Interval to notify again the firing alerts, 0 to notify them once
+	AlertJournalFile     string        // File path of the alert transitions history, optional
 
 	CleaningInterval time.Duration // Interval to clean time series
 
+	Webhook      WebhookConfig      // Webhook receiving the alerts, disabled when the url is empty
+	Alertmanager AlertmanagerConfig // Alertmanager receiving the alerts, disabled when the url is empty
+	SMTP         SMTPConfig         // Smtp server sending the alerts by email, disabled when the address is empty
+	Exec         ExecConfig         // Command run on the alert transitions, disabled when the command is empty
+
+	SilencesFile string // File path of the silences and maintenance windows, optional
+
+	APIAddress string // Address of the management api, disabled when empty
+	APITokens  Tokens // Bearer tokens of the management api, required with its address
+
+	Ingest IngestConfig // Endpoint of the api receiving the logs pushed by http, disabled without token
+
 	LogOutput string // File path to output logs of the monitor execution
 }
 
 func ReadConfiguration() (config Configuration, err error) {
-	config.LogToMonitor, err = readString("LOG_TO_MONITOR")
+	config.Mode = os.Getenv("MODE")
+	switch config.Mode {
+	case "":
+		config.Mode = FollowMode
+	case FollowMode, BatchMode, ReplayMode:
+	default:
+		return config, fmt.Errorf("cannot parse key: MODE - unknown mode %q", config.Mode)
+	}
+
+	config.ReplaySpeed, err = readSpeed("REPLAY_SPEED")
 	if err != nil {
 		return config, err
 	}
 
+	config.LogsToMonitor = splitList(os.Getenv("LOG_TO_MONITOR"))
+	config.SyslogAddresses = splitList(os.Getenv("SYSLOG_ADDRESSES"))
+
+	config.LogFormat = os.Getenv("LOG_FORMAT")
+	if len(config.LogFormat) == 0 {
+		config.LogFormat = commonlog.CommonFormat
+	}
+	_, err = commonlog.ParserFor(config.LogFormat)
+	if err != nil {
+		return config, fmt.Errorf("cannot parse key: LOG_FORMAT - %w", err)
+	}
+
+	config.LogEnvelope = os.Getenv("LOG_ENVELOPE")
+	switch config.LogEnvelope {
+	case "":
+		config.LogEnvelope = NoEnvelope
+	case NoEnvelope, DockerEnvelope, CRIEnvelope, AutoEnvelope:
+	default:
+		return config, fmt.Errorf("cannot parse key: LOG_ENVELOPE - unknown envelope %q", config.LogEnvelope)
+	}
+	for _, path := range config.LogsToMonitor {
+		// the end of the standard input ends the monitoring
+		if path == StdinPath && len(config.LogsToMonitor) > 1 && config.Mode == FollowMode {
+			return config, fmt.Errorf("cannot parse key: LOG_TO_MONITOR - the standard input must be the only log followed")
+		}
+	}
+
+	config.Parsing, err = readParseConfig()
+	if err != nil {
+		return config, err
+	}
+
+	config.DeadLetter, err = readDeadLetterConfig()
+	if err != nil {
+		return config, err
+	}
+
+	config.DiscoveryInterval, err = readOptionalDuration("LOG_DISCOVERY_INTERVAL")
+	if err != nil {
+		return config, err
+	}
+
+	config.CheckpointFile = os.Getenv("CHECKPOINT_FILE")
+	// the checkpoints of the lines handed off out of order would skip the lines not handled yet after a restart
+	if len(config.CheckpointFile) > 0 && config.Parsing.Unordered && config.Mode == FollowMode {
+		return config, fmt.Errorf("cannot parse key: PARSE_UNORDERED - the lines must be handled in order with CHECKPOINT_FILE")
+	}
+	config.CheckpointInterval, err = readOptionalDuration("CHECKPOINT_INTERVAL")
+	if err != nil {
+		return config, err
+	}
+	if config.CheckpointInterval == 0 {
+		config.CheckpointInterval = 10 * time.Second
+	}
+
 	config.LogOutput, err = readString("LOG_OUTPUT")
 	if err != nil {
 		return config, err
@@ -52,20 +166,427 @@ func ReadConfiguration() (config Configuration, err error) {
 	if err != nil {
 		return config, err
 	}
+	// the rates are computed by second of the period
+	if config.TrafficLoadPeriod < time.Second {
+		return config, fmt.Errorf("cannot parse key: TRAFFIC_LOAD_PERIOD - period must be at least 1s")
+	}
 
 	config.TrafficThreshold, err = readInt64("TRAFFIC_THRESHOLD")
 	if err != nil {
 		return config, err
 	}
 
+	config.TrafficLowThreshold, err = readOptionalInt64("TRAFFIC_LOW_THRESHOLD")
+	if err != nil {
+		return config, err
+	}
+
+	sectionThresholds, err := readThresholds("SECTION_TRAFFIC_THRESHOLDS")
+	if err != nil {
+		return config, err
+	}
+	// sections are identified without their leading slash
+	config.SectionTrafficThresholds = make(map[string]int64)
+	for section, threshold := range sectionThresholds {
+		config.SectionTrafficThresholds[strings.TrimPrefix(section, "/")] = threshold
+	}
+
+	config.HostTrafficThresholds, err = readThresholds("HOST_TRAFFIC_THRESHOLDS")
+	if err != nil {
+		return config, err
+	}
+
+	config.TerminationStateThresholds, err = readThresholds("TERMINATION_STATE_THRESHOLDS")
+	if err != nil {
+		return config, err
+	}
+
+	objectives, err := readObjectives("SLO_OBJECTIVES")
+	if err != nil {
+		return config, err
+	}
+	// sections are identified without their leading slash
+	config.SLOObjectives = trimSections(objectives)
+
+	config.SLOWindow, err = readOptionalDuration("SLO_WINDOW")
+	if err != nil {
+		return config, err
+	}
+	if config.SLOWindow == 0 {
+		config.SLOWindow = DefaultSLOWindow
+	}
+
+	targets, err := readDurations("APDEX_TARGETS")
+	if err != nil {
+		return config, err
+	}
+	config.ApdexTargets = make(map[string]time.Duration)
+	for section, target := range targets {
+		config.ApdexTargets[strings.TrimPrefix(section, "/")] = target
+	}
+
+	apdexThresholds, err := readScores("APDEX_THRESHOLDS")
+	if err != nil {
+		return config, err
+	}
+	config.ApdexThresholds = trimSections(apdexThresholds)
+
+	config.NoDataTimeout, err = readOptionalDuration("NO_DATA_TIMEOUT")
+	if err != nil {
+		return config, err
+	}
+
+	config.AlertPendingDuration, err = readOptionalDuration("ALERT_PENDING_DURATION")
+	if err != nil {
+		return config, err
+	}
+
+	config.AlertRepeatInterval, err = readOptionalDuration("ALERT_REPEAT_INTERVAL")
+	if err != nil {
+		return config, err
+	}
+
+	config.AlertJournalFile = os.Getenv("ALERT_JOURNAL_FILE")
+
 	config.CleaningInterval, err = readDuration("CLEANING_INTERVAL")
 	if err != nil {
 		return config, err
 	}
 
+	config.Webhook, err = readWebhookConfig()
+	if err != nil {
+		return config, err
+	}
+
+	config.Alertmanager, err = readAlertmanagerConfig()
+	if err != nil {
+		return config, err
+	}
+
+	config.SMTP, err = readSMTPConfig()
+	if err != nil {
+		return config, err
+	}
+
+	config.Exec, err = readExecConfig()
+	if err != nil {
+		return config, err
+	}
+
+	config.SilencesFile = os.Getenv("SILENCES_FILE")
+	config.APIAddress = os.Getenv("API_ADDRESS")
+	config.APITokens = splitList(os.Getenv("API_TOKENS"))
+	if len(config.APIAddress) > 0 && len(config.APITokens) == 0 {
+		return config, fmt.Errorf("key API_TOKENS not found - required by API_ADDRESS")
+	}
+
+	config.Ingest, err = readIngestConfig()
+	if err != nil {
+		return config, err
+	}
+	if len(config.Ingest.Tokens) > 0 && len(config.APIAddress) == 0 {
+		return config, fmt.Errorf("key API_ADDRESS not found - required by INGEST_TOKENS")
+	}
+
+	// the syslog and ingest sources are only used in follow mode
+	pushed := len(config.SyslogAddresses) > 0 || len(config.Ingest.Tokens) > 0
+	if len(config.LogsToMonitor) == 0 && (!pushed || config.Mode != FollowMode) {
+		return config, fmt.Errorf("key LOG_TO_MONITOR not found")
+	}
+
+	return config, nil
+}
+
+// readIngestConfig reads the optional configuration of the ingest endpoint
+func readIngestConfig() (config IngestConfig, err error) {
+	config.Tokens = splitList(os.Getenv("INGEST_TOKENS"))
+	if len(config.Tokens) == 0 {
+		return config, nil
+	}
+
+	config.MaxBodySize, err = readOptionalInt64("INGEST_MAX_BODY_SIZE")
+	if err != nil {
+		return config, err
+	}
+
+	return config, nil
+}
+
+// readWebhookConfig reads the optional configuration of the webhook notifier
+func readWebhookConfig() (config WebhookConfig, err error) {
+	config.URL = os.Getenv("WEBHOOK_URL")
+	if len(config.URL) == 0 {
+		return config, nil
+	}
+
+	config.Template, err = readOptionalFile("WEBHOOK_TEMPLATE_FILE")
+	if err != nil {
+		return config, err
+	}
+
+	config.Timeout, err = readOptionalDuration("WEBHOOK_TIMEOUT")
+	if err != nil {
+		return config, err
+	}
+
+	config.MaxRetries = WebhookRetriesUnset
+	if len(os.Getenv("WEBHOOK_MAX_RETRIES")) > 0 {
+		retries, err := readInt64("WEBHOOK_MAX_RETRIES")
+		if err != nil {
+			return config, err
+		}
+		if retries < 0 {
+			return config, fmt.Errorf("cannot parse key: WEBHOOK_MAX_RETRIES - retries must be positive")
+		}
+		config.MaxRetries = int(retries)
+	}
+
+	queueSize, err := readOptionalInt64("WEBHOOK_QUEUE_SIZE")
+	if err != nil {
+		return config, err
+	}
+	config.QueueSize = int(queueSize)
+
+	return config, nil
+}
+
+// readAlertmanagerConfig reads the optional configuration of the alertmanager notifier
+func readAlertmanagerConfig() (config AlertmanagerConfig, err error) {
+	config.URL = os.Getenv("ALERTMANAGER_URL")
+	if len(config.URL) == 0 {
+		return config, nil
+	}
+
+	config.GeneratorURL = os.Getenv("ALERTMANAGER_GENERATOR_URL")
+
+	config.ResendInterval, err = readOptionalDuration("ALERTMANAGER_RESEND_INTERVAL")
+	if err != nil {
+		return config, err
+	}
+
+	config.Timeout, err = readOptionalDuration("ALERTMANAGER_TIMEOUT")
+	if err != nil {
+		return config, err
+	}
+
+	return config, nil
+}
+
+// readSMTPConfig reads the optional configuration of the smtp notifier
+func readSMTPConfig() (config SMTPConfig, err error) {
+	config.Address = os.Getenv("SMTP_ADDRESS")
+	if len(config.Address) == 0 {
+		return config, nil
+	}
+
+	config.From, err = readString("SMTP_FROM")
+	if err != nil {
+		return config, err
+	}
+
+	config.Recipients, err = readRecipients("SMTP_RECIPIENTS")
+	if err != nil {
+		return config, err
+	}
+
+	config.Username = os.Getenv("SMTP_USERNAME")
+	config.Password = os.Getenv("SMTP_PASSWORD")
+
+	config.StartTLS, err = readOptionalBool("SMTP_STARTTLS")
+	if err != nil {
+		return config, err
+	}
+
+	config.DigestInterval, err = readOptionalDuration("SMTP_DIGEST_INTERVAL")
+	if err != nil {
+		return config, err
+	}
+
+	config.TextTemplate, err = readOptionalFile("SMTP_TEXT_TEMPLATE_FILE")
+	if err != nil {
+		return config, err
+	}
+
+	config.HTMLTemplate, err = readOptionalFile("SMTP_HTML_TEMPLATE_FILE")
+	if err != nil {
+		return config, err
+	}
+
 	return config, nil
 }
 
+// readCommand reads a command and its arguments, separated by spaces or as a JSON array
+// when the arguments contain spaces, such as ["/opt/scale pool.sh", "web"]
+func readCommand(key string) ([]string, error) {
+	raw := strings.TrimSpace(os.Getenv(key))
+	if !strings.HasPrefix(raw, "[") {
+		return strings.Fields(raw), nil
+	}
+
+	var command []string
+	err := json.Unmarshal([]byte(raw), &command)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse key: %s - %w", key, err)
+	}
+	if len(command) == 0 || len(command[0]) == 0 {
+		return nil, fmt.Errorf("cannot parse key: %s - the command is empty", key)
+	}
+	return command, nil
+}
+
+// readExecConfig reads the optional configuration of the exec notifier
+func readExecConfig() (config ExecConfig, err error) {
+	config.Command, err = readCommand("EXEC_HOOK_COMMAND")
+	if err != nil || len(config.Command) == 0 {
+		return config, err
+	}
+
+	config.Timeout, err = readOptionalDuration("EXEC_HOOK_TIMEOUT")
+	if err != nil {
+		return config, err
+	}
+
+	concurrency, err := readOptionalInt64("EXEC_HOOK_CONCURRENCY")
+	if err != nil {
+		return config, err
+	}
+	config.Concurrency = int(concurrency)
+
+	return config, nil
+}
+
+// readParseConfig reads the optional configuration of the parse workers
+func readParseConfig() (config ParseConfig, err error) {
+	workers, err := readOptionalInt64("PARSE_WORKERS")
+	if err != nil {
+		return config, err
+	}
+	if workers < 0 {
+		return config, fmt.Errorf("cannot parse key: PARSE_WORKERS - workers must be positive")
+	}
+	config.Workers = int(workers)
+
+	queueSize, err := readOptionalInt64("PARSE_QUEUE_SIZE")
+	if err != nil {
+		return config, err
+	}
+	if queueSize < 0 {
+		return config, fmt.Errorf("cannot parse key: PARSE_QUEUE_SIZE - queue size must be positive")
+	}
+	config.QueueSize = int(queueSize)
+
+	config.Unordered, err = readOptionalBool("PARSE_UNORDERED")
+	if err != nil {
+		return config, err
+	}
+
+	config.OverflowPolicy = os.Getenv("PARSE_OVERFLOW_POLICY")
+	switch config.OverflowPolicy {
+	case "", BlockPolicy, DropOldestPolicy, SamplePolicy:
+	default:
+		return config, fmt.Errorf("cannot parse key: PARSE_OVERFLOW_POLICY - unknown policy %q", config.OverflowPolicy)
+	}
+
+	sampleRate, err := readOptionalInt64("PARSE_SAMPLE_RATE")
+	if err != nil {
+		return config, err
+	}
+	if sampleRate < 0 {
+		return config, fmt.Errorf("cannot parse key: PARSE_SAMPLE_RATE - sample rate must be positive")
+	}
+	config.SampleRate = int(sampleRate)
+
+	return config, nil
+}
+
+// readRecipients reads the recipients by rule formatted as "rule1=a@mail.com,b@mail.com;*=c@mail.com"
+func readRecipients(key string) (map[string][]string, error) {
+	raw, err := readString(key)
+	if err != nil {
+		return nil, err
+	}
+
+	recipients := make(map[string][]string)
+	for _, item := range strings.Split(raw, ";") {
+		separator := strings.Index(item, "=")
+		if separator < 0 {
+			// recipients of all the rules
+			recipients[AnyKey] = append(recipients[AnyKey], splitList(item)...)
+			continue
+		}
+
+		rule := strings.TrimSpace(item[:separator])
+		recipients[rule] = append(recipients[rule], splitList(item[separator+1:])...)
+	}
+
+	return recipients, nil
+}
+
+// splitList splits a comma separated list and ignores the empty items
+func splitList(raw string) []string {
+	var items []string
+	for _, item := range strings.Split(raw, ",") {
+		item = strings.TrimSpace(item)
+		if len(item) > 0 {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+// readOptionalFile reads the content of the file whose path is set in the key, it returns an empty content when the key is not set
+func readOptionalFile(key string) (string, error) {
+	path := os.Getenv(key)
+	if len(path) == 0 {
+		return "", nil
+	}
+
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("cannot read key: %s - err %w", key, err)
+	}
+
+	return string(raw), nil
+}
+
+// readOptionalBool reads a boolean which can be omitted, it returns false when the key is not set
+func readOptionalBool(key string) (bool, error) {
+	raw := os.Getenv(key)
+	if len(raw) == 0 {
+		return false, nil
+	}
+
+	value, err := strconv.ParseBool(raw)
+	if err != nil {
+		return false, fmt.Errorf("cannot parse key: %s - err %w", key, err)
+	}
+
+	return value, nil
+}
+
+// readSpeed reads an optional replay speed formatted as "10x" or "max" to replay as fast as possible,
+// it returns 1 when the key is not set
+func readSpeed(key string) (float64, error) {
+	raw := os.Getenv(key)
+	switch raw {
+	case "":
+		return 1, nil
+	case "max":
+		return 0, nil
+	}
+
+	speed, err := strconv.ParseFloat(strings.TrimSuffix(raw, "x"), 64)
+	if err != nil {
+		return 0, fmt.Errorf("cannot parse key: %s - err %w", key, err)
+	}
+	if speed <= 0 {
+		return 0, fmt.Errorf("cannot parse key: %s - speed must be positive", key)
+	}
+
+	return speed, nil
+}
+
 func readString(key string) (string, error) {
 	raw := os.Getenv(key)
 	if len(raw) == 0 {
@@ -116,3 +637,163 @@ func readInt64(key string) (int64, error) {
 
 	return value, nil
 }
+
+// readOptionalDuration reads a duration which can be omitted, it returns 0 when the key is not set
+func readOptionalDuration(key string) (time.Duration, error) {
+	if len(os.Getenv(key)) == 0 {
+		return 0, nil
+	}
+
+	return readDuration(key)
+}
+
+// readOptionalInt64 reads an integer which can be omitted, it returns 0 when the key is not set
+func readOptionalInt64(key string) (int64, error) {
+	if len(os.Getenv(key)) == 0 {
+		return 0, nil
+	}
+
+	return readInt64(key)
+}
+
+// readThresholds reads an optional list of thresholds formatted as "key1=threshold1,key2=threshold2"
+func readThresholds(key string) (map[string]int64, error) {
+	thresholds := make(map[string]int64)
+
+	raw := os.Getenv(key)
+	if len(raw) == 0 {
+		return thresholds, nil
+	}
+
+	for _, item := range strings.Split(raw, ",") {
+		separator := strings.LastIndex(item, "=")
+		if separator <= 0 {
+			return nil, fmt.Errorf("cannot parse key: %s - invalid threshold %q", key, item)
+		}
+
+		value, err := strconv.ParseInt(strings.TrimSpace(item[separator+1:]), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse key: %s - err %w", key, err)
+		}
+		thresholds[strings.TrimSpace(item[:separator])] = value
+	}
+
+	return thresholds, nil
+}
+
+// readPairs reads an optional list formatted as "key1=value1,key2=value2"
+func readPairs(key string) (map[string]string, error) {
+	pairs := make(map[string]string)
+
+	raw := os.Getenv(key)
+	if len(raw) == 0 {
+		return pairs, nil
+	}
+
+	for _, item := range strings.Split(raw, ",") {
+		separator := strings.LastIndex(item, "=")
+		if separator <= 0 {
+			return nil, fmt.Errorf("cannot parse key: %s - invalid item %q", key, item)
+		}
+		pairs[strings.TrimSpace(item[:separator])] = strings.TrimSpace(item[separator+1:])
+	}
+
+	return pairs, nil
+}
+
+// readObjectives reads an optional list of objectives in percent formatted as "key1=99.9,key2=99.5",
+// the objectives are returned as ratios
+func readObjectives(key string) (map[string]float64, error) {
+	pairs, err := readPairs(key)
+	if err != nil {
+		return nil, err
+	}
+
+	objectives := make(map[string]float64)
+	for name, raw := range pairs {
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse key: %s - err %w", key, err)
+		}
+		if value <= 0 || value >= 100 {
+			return nil, fmt.Errorf("cannot parse key: %s - objective %q must be between 0 and 100 percent", key, raw)
+		}
+		objectives[name] = value / 100
+	}
+
+	return objectives, nil
+}
+
+// readScores reads an optional list of scores between 0 and 1 formatted as "key1=0.8,key2=0.5"
+func readScores(key string) (map[string]float64, error) {
+	pairs, err := readPairs(key)
+	if err != nil {
+		return nil, err
+	}
+
+	scores := make(map[string]float64)
+	for name, raw := range pairs {
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse key: %s - err %w", key, err)
+		}
+		if value < 0 || value > 1 {
+			return nil, fmt.Errorf("cannot parse key: %s - score %q must be between 0 and 1", key, raw)
+		}
+		scores[name] = value
+	}
+
+	return scores, nil
+}
+
+// readDurations reads an optional list of durations formatted as "key1=500ms,key2=1s"
+func readDurations(key string) (map[string]time.Duration, error) {
+	pairs, err := readPairs(key)
+	if err != nil {
+		return nil, err
+	}
+
+	durations := make(map[string]time.Duration)
+	for name, raw := range pairs {
+		value, err := time.ParseDuration(raw)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse key: %s - err %w", key, err)
+		}
+		durations[name] = value
+	}
+
+	return durations, nil
+}
+
+// trimSections removes the leading slash of the sections used as keys
+func trimSections(values map[string]float64) map[string]float64 {
+	trimmed := make(map[string]float64)
+	for section, value := range values {
+		trimmed[strings.TrimPrefix(section, "/")] = value
+	}
+	return trimmed
+}
+
+// readDeadLetterConfig reads the optional configuration of the file of the rejected lines
+func readDeadLetterConfig() (config DeadLetterConfig, err error) {
+	config.Path = os.Getenv("DEAD_LETTER_FILE")
+
+	config.MaxSize, err = readOptionalInt64("DEAD_LETTER_MAX_SIZE")
+	if err != nil {
+		return config, err
+	}
+	if config.MaxSize < 0 {
+		return config, fmt.Errorf("cannot parse key: DEAD_LETTER_MAX_SIZE - size must be positive")
+	}
+
+	maxBackups, err := readOptionalInt64("DEAD_LETTER_MAX_BACKUPS")
+	if err != nil {
+		return config, err
+	}
+	if maxBackups < 0 {
+		return config, fmt.Errorf("cannot parse key: DEAD_LETTER_MAX_BACKUPS - backups must be positive")
+	}
+	config.MaxBackups = int(maxBackups)
+
+	return config, nil
+}
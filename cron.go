@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronSchedule is a cron expression with the fields: minute hour day-of-month month day-of-week
+type cronSchedule struct {
+	minutes  uint64
+	hours    uint64
+	days     uint64
+	months   uint64
+	weekdays uint64
+
+	// the day matches the day-of-month or the day-of-week when both are restricted, as cron does
+	anyDay     bool
+	anyWeekday bool
+}
+
+// bounds of the cron fields
+var cronFields = []struct {
+	name     string
+	min, max int
+}{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7},
+}
+
+// parseCron parses a cron expression like "30 2 * * 1-5"
+func parseCron(expression string) (*cronSchedule, error) {
+	fields := strings.Fields(expression)
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("invalid cron expression %q: expected %v fields", expression, len(cronFields))
+	}
+
+	values := make([]uint64, len(fields))
+	for i, field := range fields {
+		bounds := cronFields[i]
+		value, err := parseCronField(field, bounds.min, bounds.max)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cron expression %q: %s: %w", expression, bounds.name, err)
+		}
+		values[i] = value
+	}
+
+	// sunday is 0 or 7
+	weekdays := values[4]
+	if weekdays&(1<<7) != 0 {
+		weekdays |= 1
+	}
+
+	return &cronSchedule{
+		minutes:    values[0],
+		hours:      values[1],
+		days:       values[2],
+		months:     values[3],
+		weekdays:   weekdays,
+		anyDay:     fields[2] == "*",
+		anyWeekday: fields[4] == "*",
+	}, nil
+}
+
+// parseCronField parses a comma separated list of values, ranges and steps into a set of bits
+func parseCronField(field string, min, max int) (uint64, error) {
+	var set uint64
+	for _, item := range strings.Split(field, ",") {
+		step := 1
+		if separator := strings.Index(item, "/"); separator >= 0 {
+			var err error
+			step, err = strconv.Atoi(item[separator+1:])
+			if err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step %q", item)
+			}
+			item = item[:separator]
+		}
+
+		start, end := min, max
+		switch {
+		case item == "*":
+		case strings.Contains(item, "-"):
+			bounds := strings.SplitN(item, "-", 2)
+			var err error
+			start, err = strconv.Atoi(bounds[0])
+			if err != nil {
+				return 0, fmt.Errorf("invalid range %q", item)
+			}
+			end, err = strconv.Atoi(bounds[1])
+			if err != nil {
+				return 0, fmt.Errorf("invalid range %q", item)
+			}
+		default:
+			value, err := strconv.Atoi(item)
+			if err != nil {
+				return 0, fmt.Errorf("invalid value %q", item)
+			}
+			start, end = value, value
+		}
+
+		if start < min || end > max || start > end {
+			return 0, fmt.Errorf("%q out of range %v-%v", item, min, max)
+		}
+
+		for value := start; value <= end; value += step {
+			set |= 1 << uint(value)
+		}
+	}
+
+	return set, nil
+}
+
+// matches checks if the schedule triggers at the minute of the time
+func (c *cronSchedule) matches(t time.Time) bool {
+	if c.minutes&(1<<uint(t.Minute())) == 0 || c.hours&(1<<uint(t.Hour())) == 0 || c.months&(1<<uint(t.Month())) == 0 {
+		return false
+	}
+
+	day := c.days&(1<<uint(t.Day())) != 0
+	weekday := c.weekdays&(1<<uint(t.Weekday())) != 0
+	if c.anyDay || c.anyWeekday {
+		return day && weekday
+	}
+	return day || weekday
+}
+
+// activeSince checks if the schedule triggered during the duration before the time
+func (c *cronSchedule) activeSince(t time.Time, duration time.Duration) bool {
+	minute := t.Truncate(time.Minute)
+	for start := minute; t.Sub(start) < duration; start = start.Add(-time.Minute) {
+		if c.matches(start) {
+			return true
+		}
+	}
+
+	return false
+}
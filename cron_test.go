@@ -0,0 +1,117 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCronSchedule_Matches(t *testing.T) {
+	type testCase struct {
+		Expression string
+		Time       time.Time
+		Expected   bool
+	}
+
+	// monday
+	monday := time.Date(2020, 02, 24, 2, 30, 0, 0, time.UTC)
+
+	cases := map[string]testCase{
+		"every minute": {
+			Expression: "* * * * *",
+			Time:       monday,
+			Expected:   true,
+		},
+		"fixed time": {
+			Expression: "30 2 * * *",
+			Time:       monday,
+			Expected:   true,
+		},
+		"other minute": {
+			Expression: "31 2 * * *",
+			Time:       monday,
+			Expected:   false,
+		},
+		"week days range": {
+			Expression: "30 2 * * 1-5",
+			Time:       monday,
+			Expected:   true,
+		},
+		"sunday as 7": {
+			Expression: "30 2 * * 7",
+			Time:       monday.Add(-24 * time.Hour),
+			Expected:   true,
+		},
+		"steps": {
+			Expression: "*/15 */2 * * *",
+			Time:       monday,
+			Expected:   true,
+		},
+		"list of days of month": {
+			Expression: "30 2 1,15 * *",
+			Time:       monday,
+			Expected:   false,
+		},
+		"day of month or day of week": {
+			Expression: "30 2 1 * 1",
+			Time:       monday,
+			Expected:   true,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			schedule, err := parseCron(c.Expression)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			actual := schedule.matches(c.Time)
+			if c.Expected != actual {
+				t.Fatal("unexpected match", "expected", c.Expected, "actual", actual)
+			}
+		})
+	}
+}
+
+func TestParseCron_Invalid(t *testing.T) {
+	cases := map[string]string{
+		"missing field":      "* * * *",
+		"out of range":       "60 * * * *",
+		"invalid range":      "* 5-2 * * *",
+		"invalid step":       "*/0 * * * *",
+		"not a number":       "a * * * *",
+		"day of month zero":  "* * 0 * *",
+		"invalid month list": "* * * 1,13 *",
+	}
+
+	for name, expression := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := parseCron(expression)
+			if err == nil {
+				t.Fatal("expected error not occurred", "expression", expression)
+			}
+		})
+	}
+}
+
+func TestCronSchedule_ActiveSince(t *testing.T) {
+	// every day at 02:00 during 1 hour
+	schedule, err := parseCron("0 2 * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[time.Time]bool{
+		time.Date(2020, 02, 24, 1, 59, 0, 0, time.UTC): false,
+		time.Date(2020, 02, 24, 2, 0, 0, 0, time.UTC):  true,
+		time.Date(2020, 02, 24, 2, 59, 0, 0, time.UTC): true,
+		time.Date(2020, 02, 24, 3, 0, 0, 0, time.UTC):  false,
+	}
+
+	for at, expected := range cases {
+		actual := schedule.activeSince(at, time.Hour)
+		if expected != actual {
+			t.Fatal("unexpected active window", "at", at, "expected", expected, "actual", actual)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	defaultDeadLetterMaxSize    = 10 * 1024 * 1024
+	defaultDeadLetterMaxBackups = 3
+)
+
+// DeadLetterConfig configures the file of the rejected lines
+type DeadLetterConfig struct {
+	Path       string // File path of the rejected lines, disabled when empty
+	MaxSize    int64  // Size in bytes from which the file is rotated, 10MB by default
+	MaxBackups int    // Number of rotated files kept, 3 by default
+}
+
+// RejectedLine is a line which cannot be handled by the monitor
+type RejectedLine struct {
+	RejectedAt time.Time `json:"rejected_at"`
+	Source     string    `json:"source,omitempty"`
+	Reason     string    `json:"reason"`
+	Error      string    `json:"error"`
+	Line       string    `json:"line"`
+}
+
+// DeadLetter records the rejected lines in a file of JSON lines for later inspection.
+// The file is rotated once it reaches its maximum size: path becomes path.1, path.1 becomes path.2 and so on.
+type DeadLetter struct {
+	sync.Mutex
+	config DeadLetterConfig
+	file   *os.File
+	size   int64
+}
+
+func OpenDeadLetter(config DeadLetterConfig) (*DeadLetter, error) {
+	if config.MaxSize == 0 {
+		config.MaxSize = defaultDeadLetterMaxSize
+	}
+	if config.MaxBackups == 0 {
+		config.MaxBackups = defaultDeadLetterMaxBackups
+	}
+
+	d := &DeadLetter{config: config}
+	err := d.open()
+	if err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+// Record appends the rejected line, the file is rotated first when the line would exceed its maximum size.
+// The line is still appended when the rotation fails.
+func (d *DeadLetter) Record(line RejectedLine) error {
+	raw, err := json.Marshal(line)
+	if err != nil {
+		return fmt.Errorf("cannot encode rejected line: %w", err)
+	}
+	raw = append(raw, '\n')
+
+	d.Lock()
+	defer d.Unlock()
+
+	var rotateErr error
+	if d.size > 0 && d.size+int64(len(raw)) > d.config.MaxSize {
+		rotateErr = d.rotate()
+	}
+
+	n, err := d.file.Write(raw)
+	d.size += int64(n)
+	if rotateErr != nil {
+		return rotateErr
+	}
+	return err
+}
+
+// Close closes the dead letter file
+func (d *DeadLetter) Close() error {
+	d.Lock()
+	defer d.Unlock()
+
+	return d.file.Close()
+}
+
+func (d *DeadLetter) open() error {
+	file, err := os.OpenFile(d.config.Path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("cannot open dead letter file: %w", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("cannot open dead letter file: %w", err)
+	}
+
+	d.file = file
+	d.size = info.Size()
+	return nil
+}
+
+// rotate shifts the backups of the file, the oldest one is overwritten, and starts a new file.
+// The file is opened again when the rotation fails so the next lines are still recorded.
+func (d *DeadLetter) rotate() error {
+	err := d.file.Close()
+	if err != nil {
+		err = fmt.Errorf("cannot close dead letter file: %w", err)
+	} else {
+		err = d.shift()
+	}
+
+	openErr := d.open()
+	if err != nil {
+		return err
+	}
+	return openErr
+}
+
+// shift renames the file and its backups to the next backups
+func (d *DeadLetter) shift() error {
+	for i := d.config.MaxBackups - 1; i > 0; i-- {
+		err := os.Rename(d.backup(i), d.backup(i+1))
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("cannot rotate dead letter file: %w", err)
+		}
+	}
+	err := os.Rename(d.config.Path, d.backup(1))
+	if err != nil {
+		return fmt.Errorf("cannot rotate dead letter file: %w", err)
+	}
+
+	return nil
+}
+
+// backup returns the path of the nth rotated file
+func (d *DeadLetter) backup(n int) string {
+	return fmt.Sprintf("%s.%v", d.config.Path, n)
+}
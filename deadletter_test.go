@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDeadLetter_Record(t *testing.T) {
+	dir, err := ioutil.TempDir("", "deadletter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "rejected.jsonl")
+	rejectedAt := time.Date(2020, 02, 24, 2, 0, 0, 0, time.UTC)
+	lines := make([]RejectedLine, 7)
+	for i := range lines {
+		lines[i] = RejectedLine{RejectedAt: rejectedAt, Source: "access.log", Reason: "bad_status", Error: "invalid status format", Line: poolLine(i)}
+	}
+	raw, err := json.Marshal(lines[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// two lines by file
+	deadLetter, err := OpenDeadLetter(DeadLetterConfig{Path: path, MaxSize: int64(2*len(raw) + 2), MaxBackups: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range lines {
+		err := deadLetter.Record(line)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = deadLetter.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the oldest file is overwritten by the rotations
+	expectedFiles := map[string][]RejectedLine{
+		path:        lines[6:],
+		path + ".1": lines[4:6],
+		path + ".2": lines[2:4],
+	}
+	for file, expected := range expectedFiles {
+		actual := readRejectedLines(t, file)
+		if !reflect.DeepEqual(expected, actual) {
+			t.Fatal("unexpected rejected lines", "file", file, "expected", expected, "actual", actual)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Fatal("unexpected backup", "err", err)
+	}
+
+	// the size of the existing file is counted after a restart
+	deadLetter, err = OpenDeadLetter(DeadLetterConfig{Path: path, MaxSize: int64(2*len(raw) + 2), MaxBackups: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer deadLetter.Close()
+	for _, line := range lines[:2] {
+		err := deadLetter.Record(line)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected := []RejectedLine{lines[6], lines[0]}
+	if actual := readRejectedLines(t, path+".1"); !reflect.DeepEqual(expected, actual) {
+		t.Fatal("unexpected rejected lines", "expected", expected, "actual", actual)
+	}
+	if actual := readRejectedLines(t, path); !reflect.DeepEqual(lines[1:2], actual) {
+		t.Fatal("unexpected rejected lines", "expected", lines[1:2], "actual", actual)
+	}
+}
+
+func readRejectedLines(t *testing.T, path string) []RejectedLine {
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	var lines []RejectedLine
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var line RejectedLine
+		err := json.Unmarshal(scanner.Bytes(), &line)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines = append(lines, line)
+	}
+	if scanner.Err() != nil {
+		t.Fatal(scanner.Err())
+	}
+
+	return lines
+}
+
+func TestDeadLetter_RecordRotationFailed(t *testing.T) {
+	dir, err := ioutil.TempDir("", "deadletter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// the backup cannot be replaced by the file while it is a directory which is not empty
+	path := filepath.Join(dir, "rejected.jsonl")
+	err = os.MkdirAll(filepath.Join(path+".1", "backup"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deadLetter, err := OpenDeadLetter(DeadLetterConfig{Path: path, MaxSize: 1, MaxBackups: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer deadLetter.Close()
+
+	lines := make([]RejectedLine, 3)
+	for i := range lines {
+		lines[i] = RejectedLine{RejectedAt: time.Date(2020, 02, 24, 2, 0, 0, 0, time.UTC), Reason: "bad_date", Line: poolLine(i)}
+		err := deadLetter.Record(lines[i])
+		if (err != nil) != (i > 0) {
+			t.Fatal("unexpected error", "line", i, "actual", err)
+		}
+	}
+
+	// the lines are still recorded in the file not rotated
+	if actual := readRejectedLines(t, path); !reflect.DeepEqual(lines, actual) {
+		t.Fatal("unexpected rejected lines", "expected", lines, "actual", actual)
+	}
+
+	// the rotation succeeds once the backup can be replaced
+	err = os.RemoveAll(path + ".1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = deadLetter.Record(lines[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual := readRejectedLines(t, path+".1"); !reflect.DeepEqual(lines, actual) {
+		t.Fatal("unexpected rotated lines", "expected", lines, "actual", actual)
+	}
+	if actual := readRejectedLines(t, path); !reflect.DeepEqual(lines[:1], actual) {
+		t.Fatal("unexpected rejected lines", "expected", lines[:1], "actual", actual)
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Envelopes wrapping the log lines written by the container runtimes
+const (
+	NoEnvelope     = "none"   // the lines are the access logs
+	DockerEnvelope = "docker" // json-file driver: {"log":"...\n","stream":"stdout","time":"..."}
+	CRIEnvelope    = "cri"    // CRI format: <time> <stream> <P|F> <message>
+	AutoEnvelope   = "auto"   // the envelope is detected on each line
+)
+
+// Labels of the events read from the container logs
+const (
+	PodLabel       = "pod"
+	NamespaceLabel = "namespace"
+	ContainerLabel = "container"
+)
+
+// envelopeDecoder unwraps the access log lines from the envelopes of the container runtimes.
+// The runtimes split the long lines in partial records, they are reassembled before being parsed.
+type envelopeDecoder struct {
+	envelope string
+
+	partials map[string]string            // beginning of the lines by source and stream
+	labels   map[string]map[string]string // labels of the container by source
+}
+
+func newEnvelopeDecoder(envelope string) *envelopeDecoder {
+	return &envelopeDecoder{
+		envelope: envelope,
+		partials: make(map[string]string),
+		labels:   make(map[string]map[string]string),
+	}
+}
+
+// decode returns the line unwrapped with the labels of its container,
+// it returns false when the record is the beginning of a line which is not complete yet
+func (d *envelopeDecoder) decode(line LogLine) (LogLine, bool) {
+	if line.Err != nil || line.Event != nil {
+		return line, true
+	}
+
+	envelope := d.envelope
+	if envelope == AutoEnvelope {
+		envelope = detectEnvelope(line.Text)
+	}
+
+	var stream, message string
+	var partial bool
+	var err error
+	switch envelope {
+	case DockerEnvelope:
+		stream, message, partial, err = decodeDocker(line.Text)
+	case CRIEnvelope:
+		stream, message, partial, err = decodeCRI(line.Text)
+	default:
+		return line, true
+	}
+	if err != nil {
+		line.Err = fmt.Errorf("invalid %s envelope: %w", envelope, err)
+		return line, true
+	}
+
+	key := line.Source + "|" + stream
+	message = d.partials[key] + message
+	if partial && len(message) < maxLineSize {
+		d.partials[key] = message
+		return line, false
+	}
+	delete(d.partials, key)
+
+	line.Text = message
+	line.Labels = mergeLabels(line.Labels, d.containerLabels(line.Source))
+	return line, true
+}
+
+// containerLabels returns the labels of the container writing the log file
+func (d *envelopeDecoder) containerLabels(path string) map[string]string {
+	labels, ok := d.labels[path]
+	if !ok {
+		labels = parseContainerPath(path)
+		d.labels[path] = labels
+	}
+	return labels
+}
+
+// detectEnvelope returns the envelope of the line
+func detectEnvelope(text string) string {
+	if strings.HasPrefix(text, "{") {
+		return DockerEnvelope
+	}
+
+	fields := strings.SplitN(text, " ", 4)
+	if len(fields) == 4 && (fields[1] == "stdout" || fields[1] == "stderr") && (fields[2] == "P" || fields[2] == "F") {
+		return CRIEnvelope
+	}
+	return NoEnvelope
+}
+
+// dockerRecord is a record of the json-file logging driver
+type dockerRecord struct {
+	Log    *string `json:"log"`
+	Stream string  `json:"stream"`
+}
+
+// decodeDocker returns the message of the record, partial when it does not end with a new line
+func decodeDocker(text string) (string, string, bool, error) {
+	var record dockerRecord
+	err := json.Unmarshal([]byte(text), &record)
+	if err != nil {
+		return "", "", false, err
+	}
+	if record.Log == nil {
+		return "", "", false, fmt.Errorf("log field not found")
+	}
+
+	message := *record.Log
+	if !strings.HasSuffix(message, "\n") {
+		return record.Stream, message, true, nil
+	}
+	return record.Stream, strings.TrimRight(message, "\r\n"), false, nil
+}
+
+// decodeCRI returns the message of the record, partial when its tag is P
+func decodeCRI(text string) (string, string, bool, error) {
+	fields := strings.SplitN(text, " ", 4)
+	if len(fields) < 3 {
+		return "", "", false, fmt.Errorf("incomplete record")
+	}
+
+	var message string
+	if len(fields) == 4 {
+		message = fields[3]
+	}
+
+	switch fields[2] {
+	case "P":
+		return fields[1], message, true, nil
+	case "F":
+		return fields[1], message, false, nil
+	default:
+		return "", "", false, fmt.Errorf("unknown tag %s", fields[2])
+	}
+}
+
+// parseContainerPath returns the pod, the namespace and the container of the kubernetes log files
+// /var/log/containers/<pod>_<namespace>_<container>-<id>.log and
+// /var/log/pods/<namespace>_<pod>_<uid>/<container>/<restarts>.log, nil for the other paths
+func parseContainerPath(path string) map[string]string {
+	path = filepath.ToSlash(path)
+	base := strings.TrimSuffix(filepath.Base(path), ".log")
+
+	if parts := strings.Split(base, "_"); len(parts) == 3 {
+		container := parts[2]
+		if dash := strings.LastIndexByte(container, '-'); dash > 0 {
+			container = container[:dash]
+		}
+		return map[string]string{PodLabel: parts[0], NamespaceLabel: parts[1], ContainerLabel: container}
+	}
+
+	dirs := strings.Split(filepath.ToSlash(filepath.Dir(path)), "/")
+	if len(dirs) >= 2 {
+		if parts := strings.Split(dirs[len(dirs)-2], "_"); len(parts) == 3 {
+			return map[string]string{PodLabel: parts[1], NamespaceLabel: parts[0], ContainerLabel: dirs[len(dirs)-1]}
+		}
+	}
+
+	return nil
+}
+
+// mergeLabels returns the labels of both sets, the first one has precedence
+func mergeLabels(labels map[string]string, others map[string]string) map[string]string {
+	if len(others) == 0 {
+		return labels
+	}
+
+	merged := make(map[string]string, len(labels)+len(others))
+	for name, value := range others {
+		merged[name] = value
+	}
+	for name, value := range labels {
+		merged[name] = value
+	}
+	return merged
+}
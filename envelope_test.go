@@ -0,0 +1,123 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEnvelopeDecoder_Decode(t *testing.T) {
+	type testCase struct {
+		Envelope       string
+		Records        []string
+		ExpectedLines  []string
+		ExpectedLabels map[string]string
+		ExpectedError  bool
+	}
+
+	path := "/var/log/containers/web-7d4b9c-x2x9q_shop_nginx-4f3a2b1c.log"
+	labels := map[string]string{PodLabel: "web-7d4b9c-x2x9q", NamespaceLabel: "shop", ContainerLabel: "nginx"}
+
+	cases := map[string]testCase{
+		"docker": {
+			Envelope:       DockerEnvelope,
+			Records:        []string{`{"log":"127.0.0.1 - - [09/May/2018:16:00:39 +0000] \"GET /report HTTP/1.0\" 200 123\n","stream":"stdout","time":"2018-05-09T16:00:39.1Z"}`},
+			ExpectedLines:  []string{`127.0.0.1 - - [09/May/2018:16:00:39 +0000] "GET /report HTTP/1.0" 200 123`},
+			ExpectedLabels: labels,
+		},
+		"docker partial lines": {
+			Envelope: DockerEnvelope,
+			Records: []string{
+				`{"log":"first ","stream":"stdout","time":"2018-05-09T16:00:39.1Z"}`,
+				`{"log":"error\n","stream":"stderr","time":"2018-05-09T16:00:39.2Z"}`,
+				`{"log":"line\n","stream":"stdout","time":"2018-05-09T16:00:39.3Z"}`,
+			},
+			ExpectedLines:  []string{"error", "first line"},
+			ExpectedLabels: labels,
+		},
+		"cri partial lines": {
+			Envelope: CRIEnvelope,
+			Records: []string{
+				"2018-05-09T16:00:39.1Z stdout P first ",
+				"2018-05-09T16:00:39.2Z stdout F line",
+				"2018-05-09T16:00:39.3Z stdout F second line",
+			},
+			ExpectedLines:  []string{"first line", "second line"},
+			ExpectedLabels: labels,
+		},
+		"auto": {
+			Envelope: AutoEnvelope,
+			Records: []string{
+				`{"log":"docker line\n","stream":"stdout"}`,
+				"2018-05-09T16:00:39.2Z stdout F cri line",
+				"raw line",
+			},
+			ExpectedLines:  []string{"docker line", "cri line", "raw line"},
+			ExpectedLabels: labels,
+		},
+		"invalid docker record": {
+			Envelope:      DockerEnvelope,
+			Records:       []string{`{"stream":"stdout"}`},
+			ExpectedError: true,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			decoder := newEnvelopeDecoder(c.Envelope)
+
+			var lines []string
+			for _, record := range c.Records {
+				line, complete := decoder.decode(LogLine{Source: path, Text: record})
+				if !complete {
+					continue
+				}
+				if (line.Err != nil) != c.ExpectedError {
+					t.Fatal("unexpected error", "expected", c.ExpectedError, "actual", line.Err)
+				}
+				if line.Err != nil {
+					continue
+				}
+
+				if line.Text != "raw line" && !reflect.DeepEqual(c.ExpectedLabels, line.Labels) {
+					t.Fatal("unexpected labels", "expected", c.ExpectedLabels, "actual", line.Labels)
+				}
+				lines = append(lines, line.Text)
+			}
+
+			if !reflect.DeepEqual(c.ExpectedLines, lines) {
+				t.Fatal("unexpected lines", "expected", c.ExpectedLines, "actual", lines)
+			}
+		})
+	}
+}
+
+func TestParseContainerPath(t *testing.T) {
+	type testCase struct {
+		Path     string
+		Expected map[string]string
+	}
+
+	cases := map[string]testCase{
+		"containers directory": {
+			Path:     "/var/log/containers/web-0_shop_access-log-4f3a2b1c.log",
+			Expected: map[string]string{PodLabel: "web-0", NamespaceLabel: "shop", ContainerLabel: "access-log"},
+		},
+		"pods directory": {
+			Path:     "/var/log/pods/shop_web-0_0b1c2d3e/nginx/2.log",
+			Expected: map[string]string{PodLabel: "web-0", NamespaceLabel: "shop", ContainerLabel: "nginx"},
+		},
+		"other file": {
+			Path:     "/var/log/nginx/access.log",
+			Expected: nil,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			actual := parseContainerPath(c.Path)
+			if !reflect.DeepEqual(c.Expected, actual) {
+				t.Fatal("unexpected labels", "expected", c.Expected, "actual", actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Default values of the exec notifier
+const (
+	defaultExecTimeout     = 30 * time.Second
+	defaultExecConcurrency = 1
+	defaultExecQueueSize   = 100
+
+	// maximum output of the command written in the logs
+	maxExecOutput = 1024
+)
+
+// ExecConfig describes the command run on the alert transitions,
+// zero values are replaced by the default ones
+type ExecConfig struct {
+	Command     []string      // Command and its arguments
+	Timeout     time.Duration // The command is killed after this duration
+	Concurrency int           // Maximum number of commands running at the same time
+	QueueSize   int           // Number of alerts waiting for a command
+}
+
+// ExecNotifier runs a command on each alert transition, to trigger a local remediation for example.
+// The alert is written in JSON on the standard input of the command and set in environment variables.
+type ExecNotifier struct {
+	config ExecConfig
+
+	queue   chan Alert
+	running chan struct{} // semaphore limiting the concurrency
+	wg      sync.WaitGroup
+}
+
+// execPayload is the JSON written on the standard input of the command
+type execPayload struct {
+	Status      string            `json:"status"`
+	Fingerprint string            `json:"fingerprint"`
+	Subject     string            `json:"subject"`
+	Severity    string            `json:"severity"`
+	Labels      map[string]string `json:"labels"`
+	Alert
+}
+
+// execResult describes how the command ended
+type execResult struct {
+	ExitCode int
+	Output   []byte
+	Duration time.Duration
+}
+
+func NewExecNotifier(config ExecConfig) (*ExecNotifier, error) {
+	if len(config.Command) == 0 || len(config.Command[0]) == 0 {
+		return nil, errors.New("exec command is missing")
+	}
+	if config.Timeout == 0 {
+		config.Timeout = defaultExecTimeout
+	}
+	if config.Concurrency == 0 {
+		config.Concurrency = defaultExecConcurrency
+	}
+	if config.QueueSize == 0 {
+		config.QueueSize = defaultExecQueueSize
+	}
+
+	n := &ExecNotifier{
+		config:  config,
+		queue:   make(chan Alert, config.QueueSize),
+		running: make(chan struct{}, config.Concurrency),
+	}
+
+	n.wg.Add(1)
+	go n.dispatch()
+
+	return n, nil
+}
+
+// Notify queues the alert, it returns ErrQueueFull when the alert cannot be queued
+func (n *ExecNotifier) Notify(alert Alert) error {
+	select {
+	case n.queue <- alert:
+		return nil
+	default:
+		return fmt.Errorf("exec: %w - alert %s dropped", ErrQueueFull, alert.ID())
+	}
+}
+
+// Close stops the notifier once the commands of the queued alerts ended
+func (n *ExecNotifier) Close() error {
+	close(n.queue)
+	n.wg.Wait()
+	return nil
+}
+
+// dispatch runs a command for each queued alert without exceeding the concurrency
+func (n *ExecNotifier) dispatch() {
+	defer n.wg.Done()
+
+	for alert := range n.queue {
+		n.running <- struct{}{}
+		n.wg.Add(1)
+
+		go func(alert Alert) {
+			defer n.wg.Done()
+			defer func() { <-n.running }()
+
+			result, err := n.run(alert)
+			if err != nil {
+				log.Println("exec hook failed", "alert", alert.ID(), "exit code", result.ExitCode,
+					"duration", result.Duration, "err", err, "output", truncate(result.Output, maxExecOutput))
+				return
+			}
+
+			log.Println("exec hook succeeded", "alert", alert.ID(), "duration", result.Duration)
+		}(alert)
+	}
+}
+
+// run executes the command for the alert and waits for its end
+func (n *ExecNotifier) run(alert Alert) (execResult, error) {
+	var result execResult
+
+	payload, err := json.Marshal(execPayload{
+		Status:      alert.Status(),
+		Fingerprint: alert.Fingerprint(),
+		Subject:     alert.Subject(),
+		Severity:    alert.Severity(),
+		Labels:      alert.Labels(),
+		Alert:       alert,
+	})
+	if err != nil {
+		return result, fmt.Errorf("cannot encode alert: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), n.config.Timeout)
+	defer cancel()
+
+	var output bytes.Buffer
+	cmd := exec.CommandContext(ctx, n.config.Command[0], n.config.Command[1:]...)
+	cmd.Stdin = bytes.NewReader(payload)
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	cmd.Env = append(os.Environ(), alertEnv(alert)...)
+
+	start := time.Now()
+	err = cmd.Run()
+	result.Duration = time.Since(start)
+	result.Output = output.Bytes()
+	if cmd.ProcessState != nil {
+		result.ExitCode = cmd.ProcessState.ExitCode()
+	}
+
+	if ctx.Err() == context.DeadlineExceeded {
+		return result, fmt.Errorf("command killed after %s", n.config.Timeout)
+	}
+
+	return result, err
+}
+
+// alertEnv describes the alert in environment variables
+func alertEnv(alert Alert) []string {
+	return []string{
+		"ALERT_STATUS=" + alert.Status(),
+		"ALERT_FINGERPRINT=" + alert.Fingerprint(),
+		"ALERT_NAME=" + alert.Name,
+		"ALERT_SEVERITY=" + alert.Severity(),
+		"ALERT_SUBJECT=" + alert.Subject(),
+		"ALERT_SCOPE=" + alert.Scope,
+		"ALERT_KEY=" + alert.Key,
+		"ALERT_HITS=" + strconv.FormatInt(alert.Hits, 10),
+		"ALERT_AVERAGE_RATE=" + strconv.FormatInt(alert.AverageRate, 10),
+		"ALERT_THRESHOLD=" + strconv.FormatInt(alert.Threshold, 10),
+		"ALERT_STARTS_AT=" + alert.StartsAt.Format(time.RFC3339),
+		"ALERT_TRIGGERED_AT=" + alert.TriggeredAt.Format(time.RFC3339),
+	}
+}
+
+// truncate limits the size of an output to display it
+func truncate(output []byte, max int) string {
+	if len(output) <= max {
+		return string(output)
+	}
+	return string(output[:max]) + "..."
+}
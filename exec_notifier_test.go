@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExecNotifier_Run(t *testing.T) {
+	triggeredAt := time.Date(2006, 01, 02, 15, 04, 05, 000, time.UTC)
+	alert := Alert{Name: HighTrafficAlert, Scope: HostScope, Key: "10.1.2.3", State: AlertFiring, Hits: 18000, AverageRate: 300, Threshold: 200, TriggeredAt: triggeredAt}
+
+	type testCase struct {
+		Command          string
+		Timeout          time.Duration
+		ExpectedExitCode int
+		ExpectedOutput   string
+		ExpectedError    bool
+	}
+
+	cases := map[string]testCase{
+		"alert in environment variables": {
+			Command:        `echo "$ALERT_STATUS $ALERT_NAME $ALERT_SUBJECT $ALERT_AVERAGE_RATE $ALERT_TRIGGERED_AT"`,
+			ExpectedOutput: "firing high_traffic client 10.1.2.3 300 2006-01-02T15:04:05Z\n",
+		},
+		"failed command": {
+			Command:          `echo "cannot scale" >&2; exit 3`,
+			ExpectedExitCode: 3,
+			ExpectedOutput:   "cannot scale\n",
+			ExpectedError:    true,
+		},
+		"command killed after timeout": {
+			Command:          `exec sleep 5`,
+			Timeout:          50 * time.Millisecond,
+			ExpectedExitCode: -1,
+			ExpectedError:    true,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			n, err := NewExecNotifier(ExecConfig{Command: []string{"sh", "-c", c.Command}, Timeout: c.Timeout})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer n.Close()
+
+			result, err := n.run(alert)
+			if c.ExpectedError != (err != nil) {
+				t.Fatal("unexpected error", "expected", c.ExpectedError, "actual", err)
+			}
+
+			if c.ExpectedExitCode != result.ExitCode {
+				t.Fatal("unexpected exit code", "expected", c.ExpectedExitCode, "actual", result.ExitCode)
+			}
+
+			if c.ExpectedOutput != string(result.Output) {
+				t.Fatal("unexpected output", "expected", c.ExpectedOutput, "actual", string(result.Output))
+			}
+		})
+	}
+}
+
+func TestExecNotifier_Notify(t *testing.T) {
+	dir, err := ioutil.TempDir("", "exec_notifier")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// each command saves the alert received on its standard input
+	n, err := NewExecNotifier(ExecConfig{
+		Command:     []string{"sh", "-c", `sleep 0.2; cat > "$0/$ALERT_KEY.json"`, dir},
+		Concurrency: 2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	keys := []string{"login", "checkout", "cart", "search"}
+	for _, key := range keys {
+		err = n.Notify(Alert{Name: HighTrafficAlert, Scope: SectionScope, Key: key, State: AlertFiring})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err = n.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 4 commands with 2 running at the same time
+	elapsed := time.Since(start)
+	if elapsed < 400*time.Millisecond {
+		t.Fatal("concurrency limit not respected", "elapsed", elapsed)
+	}
+
+	for _, key := range keys {
+		raw, err := ioutil.ReadFile(filepath.Join(dir, key+".json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var payload map[string]interface{}
+		err = json.Unmarshal(raw, &payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if payload["status"] != "firing" || payload["key"] != key || payload["subject"] != "section /"+key {
+			t.Fatal("unexpected payload", "actual", strings.TrimSpace(string(raw)))
+		}
+	}
+}
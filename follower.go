@@ -0,0 +1,188 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"log"
+	"os"
+	"time"
+)
+
+// DefaultPollInterval is the interval to check a followed file for new lines, rotation and truncation
+const DefaultPollInterval = 250 * time.Millisecond
+
+// fileFollower reads the lines of a file as it grows and follows its rotations and truncations.
+// Each line is sent with the position of its end so the offset of the handled lines can be saved.
+type fileFollower struct {
+	path         string
+	pollInterval time.Duration
+
+	lines chan<- LogLine
+	stop  <-chan struct{}
+}
+
+// follow reads the file from the start position until the stop channel is closed.
+// The start position is used only if it belongs to the same file and it is not beyond its end.
+func (f *fileFollower) follow(start FilePosition, resume bool) {
+	for {
+		file, position, ok := f.open(start, resume)
+		if !ok {
+			return
+		}
+
+		next := f.read(file, position)
+		file.Close()
+		if !next {
+			return
+		}
+
+		// the file has been rotated, the new file is read from its start
+		resume = false
+	}
+}
+
+// open waits for the file to exist and opens it at the start position if it is still valid.
+// An error preventing to open the file is reported once until it changes, not at each attempt.
+func (f *fileFollower) open(start FilePosition, resume bool) (*os.File, FilePosition, bool) {
+	var reported string
+	for {
+		file, position, err := f.openAt(start, resume)
+		if err == nil {
+			return file, position, true
+		}
+		if !os.IsNotExist(err) && err.Error() != reported {
+			reported = err.Error()
+			if !f.send(LogLine{Source: f.path, Err: err}) {
+				return nil, FilePosition{}, false
+			}
+		}
+
+		if !f.wait() {
+			return nil, FilePosition{}, false
+		}
+	}
+}
+
+// openAt opens the file at the start position if it is still valid, at its start otherwise
+func (f *fileFollower) openAt(start FilePosition, resume bool) (*os.File, FilePosition, error) {
+	file, err := os.Open(f.path)
+	if err != nil {
+		return nil, FilePosition{}, err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, FilePosition{}, err
+	}
+
+	position := fileIdentity(info)
+	switch {
+	case !resume:
+	case position.Inode != start.Inode || position.Device != start.Device:
+		log.Println("log file rotated since the checkpoint, reading it from the start", "file", f.path)
+	case info.Size() < start.Offset:
+		log.Println("log file truncated since the checkpoint, reading it from the start", "file", f.path)
+	default:
+		position.Offset = start.Offset
+	}
+
+	_, err = file.Seek(position.Offset, io.SeekStart)
+	if err != nil {
+		file.Close()
+		return nil, FilePosition{}, err
+	}
+
+	return file, position, nil
+}
+
+// read sends the lines of the opened file, it returns true when the file has been rotated
+// and false when the follower is stopped
+func (f *fileFollower) read(file *os.File, position FilePosition) bool {
+	reader := bufio.NewReader(file)
+	var partial []byte
+	rotated := false
+	for {
+		data, err := reader.ReadBytes('\n')
+		if len(data) > 0 && data[len(data)-1] == '\n' {
+			line := append(partial, data...)
+			partial = nil
+
+			position.Offset += int64(len(line))
+			text := string(bytes.TrimRight(line, "\r\n"))
+			if !f.send(LogLine{Source: f.path, Text: text, Position: position}) {
+				return false
+			}
+			continue
+		}
+		partial = append(partial, data...)
+
+		if err != nil && err != io.EOF {
+			if !f.send(LogLine{Source: f.path, Err: err}) {
+				return false
+			}
+		}
+
+		if rotated {
+			// the old file is read until its end, its last line may not end with a new line
+			if len(partial) > 0 {
+				position.Offset += int64(len(partial))
+				if !f.send(LogLine{Source: f.path, Text: string(partial), Position: position}) {
+					return false
+				}
+			}
+			return true
+		}
+
+		if !f.wait() {
+			return false
+		}
+
+		info, err := os.Stat(f.path)
+		if err != nil {
+			// the file may be moved before being created again
+			continue
+		}
+
+		current := fileIdentity(info)
+		switch {
+		case current.Inode != position.Inode || current.Device != position.Device:
+			log.Println("log file rotated", "file", f.path)
+			rotated = true
+		case info.Size() < position.Offset+int64(len(partial)):
+			log.Println("log file truncated", "file", f.path)
+			_, err = file.Seek(0, io.SeekStart)
+			if err != nil {
+				// read the file again from its start
+				return true
+			}
+			reader.Reset(file)
+			partial = nil
+			position.Offset = 0
+		}
+	}
+}
+
+// send sends the line unless the follower is stopped
+func (f *fileFollower) send(line LogLine) bool {
+	select {
+	case f.lines <- line:
+		return true
+	case <-f.stop:
+		return false
+	}
+}
+
+// wait waits for the poll interval unless the follower is stopped
+func (f *fileFollower) wait() bool {
+	timer := time.NewTimer(f.pollInterval)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-f.stop:
+		return false
+	}
+}
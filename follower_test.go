@@ -0,0 +1,153 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// startFollower follows the file from the position until the returned stop function is called
+func startFollower(path string, start FilePosition, resume bool) (chan LogLine, func()) {
+	lines := make(chan LogLine)
+	stop := make(chan struct{})
+	done := make(chan struct{})
+
+	follower := &fileFollower{path: path, pollInterval: 10 * time.Millisecond, lines: lines, stop: stop}
+	go func() {
+		defer close(done)
+		follower.follow(start, resume)
+	}()
+
+	return lines, func() {
+		close(stop)
+		<-done
+	}
+}
+
+// expectLines waits for the lines of the follower
+func expectLines(t *testing.T, lines chan LogLine, expected ...string) FilePosition {
+	var position FilePosition
+	for _, text := range expected {
+		select {
+		case line := <-lines:
+			if line.Err != nil || line.Text != text {
+				t.Fatal("unexpected line", "expected", text, "actual", line)
+			}
+			position = line.Position
+		case <-time.After(5 * time.Second):
+			t.Fatal("line not received", "expected", text)
+		}
+	}
+	return position
+}
+
+func TestFileFollower_Follow(t *testing.T) {
+	dir, err := ioutil.TempDir("", "follower")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "access.log")
+	appendLine(t, path, "line 1")
+	appendLine(t, path, "line 2")
+
+	lines, stop := startFollower(path, FilePosition{}, false)
+	position := expectLines(t, lines, "line 1", "line 2")
+	if position.Offset != 14 || position.Inode == 0 {
+		t.Fatal("unexpected position", "expected", 14, "actual", position)
+	}
+
+	// a partial line is sent once complete
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = file.WriteString("line ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	_, err = file.WriteString("3\n")
+	file.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	position = expectLines(t, lines, "line 3")
+	stop()
+
+	// resumed after the last line handled
+	appendLine(t, path, "line 4")
+	lines, stop = startFollower(path, position, true)
+	expectLines(t, lines, "line 4")
+
+	// rotation: the end of the old file is read before the new file
+	appendLine(t, path, "line 5")
+	err = os.Rename(path, path+".1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	appendLine(t, path, "line 6")
+	expectLines(t, lines, "line 5", "line 6")
+
+	// truncation: the file is read again from its start
+	err = os.Truncate(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	appendLine(t, path, "line 7")
+	position = expectLines(t, lines, "line 7")
+	stop()
+	if position.Offset != 7 {
+		t.Fatal("unexpected position", "expected", 7, "actual", position)
+	}
+}
+
+func TestFileFollower_FollowRotatedSinceCheckpoint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "follower")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "access.log")
+	appendLine(t, path, "line 1")
+
+	// the checkpoint belongs to another file
+	lines, stop := startFollower(path, FilePosition{Inode: 1, Device: 1, Offset: 7}, true)
+	defer stop()
+	expectLines(t, lines, "line 1")
+}
+
+func TestFileFollower_OpenError(t *testing.T) {
+	dir, err := ioutil.TempDir("", "follower")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// the parent of the log is a file, so the log cannot be opened
+	parent := filepath.Join(dir, "access.log")
+	appendLine(t, parent, "line 1")
+	lines, stop := startFollower(filepath.Join(parent, "access.log"), FilePosition{}, false)
+	defer stop()
+
+	select {
+	case line := <-lines:
+		if line.Err == nil {
+			t.Fatal("unexpected line", "expected", "error", "actual", line)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("error not received")
+	}
+
+	// the same error is not reported at each attempt
+	select {
+	case line := <-lines:
+		t.Fatal("unexpected line", "actual", line)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -0,0 +1,19 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// fileIdentity returns the inode and the device of the file
+func fileIdentity(info os.FileInfo) FilePosition {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return FilePosition{}
+	}
+
+	return FilePosition{Inode: uint64(stat.Ino), Device: uint64(stat.Dev)}
+}
@@ -0,0 +1,13 @@
+//go:build windows
+// +build windows
+
+package main
+
+import (
+	"os"
+)
+
+// fileIdentity returns no identity on windows, the rotations are detected as truncations only
+func fileIdentity(info os.FileInfo) FilePosition {
+	return FilePosition{}
+}
@@ -4,8 +4,5 @@ go 1.13
 
 require (
 	github.com/bouk/monkey v0.0.0-20180214223050-b0daf389680b
-	github.com/hpcloud/tail v1.0.0
-	golang.org/x/sys v0.0.0-20200219091948-cb0a6d8edb6c // indirect
-	gopkg.in/fsnotify.v1 v1.4.7 // indirect
-	gopkg.in/tomb.v1 v1.0.0-20141024135613-dd632973f1e7 // indirect
+	github.com/klauspost/compress v1.10.3
 )
@@ -0,0 +1,324 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ali.ghanem/http-log-monitoring/commonlog"
+)
+
+// DefaultIngestMaxBodySize is the default size limit of a batch, once decompressed
+const DefaultIngestMaxBodySize = 10 * 1024 * 1024
+
+// DefaultIngestSource is the source of the events pushed without source parameter
+const DefaultIngestSource = "http"
+
+// maxIngestErrors is the number of rejection reasons returned for a batch
+const maxIngestErrors = 10
+
+var errBodyTooLarge = errors.New("request body too large")
+
+// IngestConfig configures the endpoint receiving the logs pushed by http
+type IngestConfig struct {
+	Tokens      Tokens // Bearer tokens accepted, the endpoint is disabled without token
+	MaxBodySize int64  // Size limit of a batch, before and after its decompression
+}
+
+// IngestSource receives the batches of logs pushed on the ingest endpoint.
+// The lines are parsed by the endpoint to count the accepted and rejected events of each batch,
+// the events are then handled by the monitor as the lines of the other sources.
+type IngestSource struct {
+	config IngestConfig
+	parse  commonlog.Parser
+
+	// Reject is called with the lines and events rejected from the batches with the reason of their error, optional
+	Reject func(line LogLine, reason string, err error)
+
+	lines chan LogLine
+	stop  chan struct{}
+}
+
+// IngestResult is the response to a batch
+type IngestResult struct {
+	Accepted int      `json:"accepted"`
+	Rejected int      `json:"rejected"`
+	Errors   []string `json:"errors,omitempty"` // reasons of the first rejections
+
+	rejections []ingestRejection
+}
+
+// ingestRejection is a line or an event of a batch which cannot be handled
+type ingestRejection struct {
+	text string
+	err  error
+}
+
+// ingestEvent is an event pushed as a JSON object
+type ingestEvent struct {
+	Host     string            `json:"host"`
+	RFC931   string            `json:"rfc931"`
+	User     string            `json:"user"`
+	Date     time.Time         `json:"date"`
+	Request  string            `json:"request"`
+	Status   int               `json:"status"`
+	Bytes    int               `json:"bytes"`
+	Duration *float64          `json:"duration"` // request time in seconds, optional
+	Labels   map[string]string `json:"labels"`
+}
+
+// NewIngestSource creates the source of the logs pushed by http, the log lines are parsed with the parser
+func NewIngestSource(config IngestConfig, parse commonlog.Parser) *IngestSource {
+	if config.MaxBodySize == 0 {
+		config.MaxBodySize = DefaultIngestMaxBodySize
+	}
+
+	return &IngestSource{
+		config: config,
+		parse:  parse,
+		lines:  make(chan LogLine),
+		stop:   make(chan struct{}),
+	}
+}
+
+// Start does nothing, the batches are received by the api server
+func (s *IngestSource) Start() error {
+	return nil
+}
+
+// Lines returns the events received, the channel is never closed
+func (s *IngestSource) Lines() <-chan LogLine {
+	return s.lines
+}
+
+// Stop rejects the batches received from now on
+func (s *IngestSource) Stop() {
+	close(s.stop)
+}
+
+// ServeHTTP receives a batch of log lines separated by new lines, or a JSON array of events when the
+// content type is application/json. The body can be compressed with gzip.
+func (s *IngestSource) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	if !s.authorized(r) {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeError(w, http.StatusUnauthorized, "invalid token")
+		return
+	}
+
+	source := r.URL.Query().Get("source")
+	if len(source) == 0 {
+		source = DefaultIngestSource
+	}
+
+	body, err := s.body(r)
+	if errors.Is(err, errBodyTooLarge) {
+		writeError(w, http.StatusRequestEntityTooLarge, err.Error())
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	defer body.Close()
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	var events []commonlog.Event
+	var result IngestResult
+	if mediaType == "application/json" {
+		events, result, err = parseEventsArray(body, s.parse)
+	} else {
+		events, result, err = parseLines(body, s.parse)
+	}
+	if errors.Is(err, errBodyTooLarge) {
+		writeError(w, http.StatusRequestEntityTooLarge, err.Error())
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if s.Reject != nil {
+		for _, rejection := range result.rejections {
+			s.Reject(LogLine{Source: source, Text: rejection.text}, commonlog.Reason(rejection.err), rejection.err)
+		}
+	}
+
+	for _, event := range events {
+		event := event
+		event.Source = source
+		select {
+		case s.lines <- LogLine{Source: source, Event: &event}:
+		case <-r.Context().Done():
+			return
+		case <-s.stop:
+			writeError(w, http.StatusServiceUnavailable, "monitor stopping")
+			return
+		}
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// authorized tells if the request has one of the tokens of the endpoint
+func (s *IngestSource) authorized(r *http.Request) bool {
+	return authorizedBearer(r, s.config.Tokens)
+}
+
+// body returns the body of the batch decompressed, limited to the maximum size before and after decompression
+func (s *IngestSource) body(r *http.Request) (io.ReadCloser, error) {
+	body := &limitedReader{ReadCloser: r.Body, remaining: s.config.MaxBodySize}
+	if r.Header.Get("Content-Encoding") != "gzip" {
+		return body, nil
+	}
+
+	decompressed, err := gzip.NewReader(body)
+	if errors.Is(err, errBodyTooLarge) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid gzip body: %w", err)
+	}
+	return &limitedReader{ReadCloser: decompressed, remaining: s.config.MaxBodySize}, nil
+}
+
+// limitedReader fails when more than the remaining bytes are read
+type limitedReader struct {
+	io.ReadCloser
+	remaining int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	n, err := l.ReadCloser.Read(p)
+	l.remaining -= int64(n)
+	if l.remaining < 0 {
+		return n, errBodyTooLarge
+	}
+	return n, err
+}
+
+// parseLines parses a log line by line of the body, the empty lines and the headers are ignored
+func parseLines(body io.Reader, parse commonlog.Parser) ([]commonlog.Event, IngestResult, error) {
+	var events []commonlog.Event
+	var result IngestResult
+
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	number := 0
+	for scanner.Scan() {
+		number++
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if len(line) == 0 {
+			continue
+		}
+
+		event, err := parse(line)
+		if errors.Is(err, commonlog.ErrHeader) {
+			continue
+		}
+		if err != nil {
+			result.reject(line, err, fmt.Sprintf("line %d: %s", number, err))
+			continue
+		}
+		events = append(events, event)
+		result.Accepted++
+	}
+
+	return events, result, scanner.Err()
+}
+
+// parseEventsArray parses a JSON array whose items are log lines or event objects
+func parseEventsArray(body io.Reader, parse commonlog.Parser) ([]commonlog.Event, IngestResult, error) {
+	var events []commonlog.Event
+	var result IngestResult
+
+	var items []json.RawMessage
+	err := json.NewDecoder(body).Decode(&items)
+	if err != nil {
+		if errors.Is(err, errBodyTooLarge) {
+			return nil, result, err
+		}
+		return nil, result, fmt.Errorf("invalid events array: %w", err)
+	}
+
+	for i, item := range items {
+		event, err := parseEventItem(item, parse)
+		if err != nil {
+			result.reject(string(item), err, fmt.Sprintf("event %d: %s", i, err))
+			continue
+		}
+		events = append(events, event)
+		result.Accepted++
+	}
+
+	return events, result, nil
+}
+
+// parseEventItem parses an item of the events array, a log line or an event object
+func parseEventItem(item json.RawMessage, parse commonlog.Parser) (commonlog.Event, error) {
+	item = bytes.TrimSpace(item)
+	if len(item) > 0 && item[0] == '"' {
+		var line string
+		err := json.Unmarshal(item, &line)
+		if err != nil {
+			return commonlog.Event{}, &commonlog.ParseError{Reason: commonlog.ErrMalformed, Err: err}
+		}
+		return parse(line)
+	}
+
+	var pushed ingestEvent
+	err := json.Unmarshal(item, &pushed)
+	if err != nil {
+		return commonlog.Event{}, &commonlog.ParseError{Reason: commonlog.ErrMalformed, Err: err}
+	}
+	if pushed.Date.IsZero() {
+		return commonlog.Event{}, &commonlog.ParseError{Reason: commonlog.ErrMissingField, Err: errors.New("date missing")}
+	}
+	if pushed.Status == 0 {
+		return commonlog.Event{}, &commonlog.ParseError{Reason: commonlog.ErrMissingField, Err: errors.New("status missing")}
+	}
+
+	section, err := commonlog.ParseSection(pushed.Request)
+	if err != nil {
+		return commonlog.Event{}, err
+	}
+
+	event := commonlog.Event{
+		Host:    pushed.Host,
+		RFC931:  pushed.RFC931,
+		User:    pushed.User,
+		Date:    pushed.Date,
+		Request: pushed.Request,
+		Status:  pushed.Status,
+		Bytes:   pushed.Bytes,
+		Section: section,
+		Labels:  pushed.Labels,
+	}
+	if pushed.Duration != nil && *pushed.Duration >= 0 {
+		event.Duration = time.Duration(*pushed.Duration * float64(time.Second))
+		event.HasDuration = true
+	}
+	return event, nil
+}
+
+// reject counts a rejected line or event and keeps the description of the first ones
+func (r *IngestResult) reject(text string, err error, description string) {
+	r.Rejected++
+	r.rejections = append(r.rejections, ingestRejection{text: text, err: err})
+	if len(r.Errors) < maxIngestErrors {
+		r.Errors = append(r.Errors, description)
+	}
+}
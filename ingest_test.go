@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ali.ghanem/http-log-monitoring/commonlog"
+)
+
+func TestIngestSource_ServeHTTP(t *testing.T) {
+	type testCase struct {
+		Token            string
+		NoScheme         bool // sends the token without the bearer scheme
+		ContentType      string
+		Body             string
+		Gzip             bool
+		ExpectedStatus   int
+		ExpectedResult   IngestResult
+		ExpectedSections []string
+		ExpectedRejected []string // reasons of the lines rejected
+	}
+
+	validLine := `127.0.0.1 - james [09/May/2018:16:00:39 +0000] "GET /report HTTP/1.0" 200 123`
+
+	cases := map[string]testCase{
+		"log lines": {
+			Token:            "secret",
+			ContentType:      "text/plain",
+			Body:             validLine + "\n\n" + "not a log line\r\n" + strings.Replace(validLine, "/report", "/api/users", 1),
+			ExpectedStatus:   http.StatusOK,
+			ExpectedResult:   IngestResult{Accepted: 2, Rejected: 1, Errors: []string{"line 3: reading date: character not found [ - event: host:not|rfc931:a|user:log|date:0001-01-01 00:00:00 +0000 UTC|request:|status:0|bytes:0"}},
+			ExpectedSections: []string{"report", "api"},
+			ExpectedRejected: []string{"missing_field"},
+		},
+		"gzip log lines": {
+			Token:            "other",
+			Body:             validLine + "\n",
+			Gzip:             true,
+			ExpectedStatus:   http.StatusOK,
+			ExpectedResult:   IngestResult{Accepted: 1},
+			ExpectedSections: []string{"report"},
+		},
+		"json array of events": {
+			Token:       "secret",
+			ContentType: "application/json; charset=utf-8",
+			Body: `[` + strings.Replace(`"`+validLine+`"`, `"GET /report HTTP/1.0"`, `\"GET /report HTTP/1.0\"`, 1) + `,
+				{"host": "10.0.0.1", "date": "2018-05-09T16:00:40Z", "request": "POST /checkout/pay HTTP/1.1", "status": 201, "bytes": 12, "duration": 0.25},
+				{"host": "10.0.0.1", "request": "GET /report HTTP/1.1", "status": 200}]`,
+			ExpectedStatus:   http.StatusOK,
+			ExpectedResult:   IngestResult{Accepted: 2, Rejected: 1, Errors: []string{"event 2: date missing"}},
+			ExpectedSections: []string{"report", "checkout"},
+			ExpectedRejected: []string{"missing_field"},
+		},
+		"invalid json": {
+			Token:          "secret",
+			ContentType:    "application/json",
+			Body:           `{"host": "10.0.0.1"}`,
+			ExpectedStatus: http.StatusBadRequest,
+		},
+		"invalid token": {
+			Token:          "guess",
+			Body:           validLine,
+			ExpectedStatus: http.StatusUnauthorized,
+		},
+		"token without bearer scheme": {
+			Token:          "secret",
+			NoScheme:       true,
+			Body:           validLine,
+			ExpectedStatus: http.StatusUnauthorized,
+		},
+		"body too large": {
+			Token:          "secret",
+			Body:           strings.Repeat(validLine+"\n", 20),
+			ExpectedStatus: http.StatusRequestEntityTooLarge,
+		},
+		"decompressed body too large": {
+			Token:          "secret",
+			Body:           strings.Repeat(validLine+"\n", 20),
+			Gzip:           true,
+			ExpectedStatus: http.StatusRequestEntityTooLarge,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			ingest := NewIngestSource(IngestConfig{Tokens: []string{"secret", "other"}, MaxBodySize: 1024}, commonlog.Parse)
+			defer ingest.Stop()
+			var lock sync.Mutex
+			var rejected []string
+			ingest.Reject = func(line LogLine, reason string, err error) {
+				lock.Lock()
+				defer lock.Unlock()
+				if line.Source != "web" || len(line.Text) == 0 || err == nil {
+					t.Error("unexpected rejected line", "actual", line, err)
+				}
+				rejected = append(rejected, reason)
+			}
+
+			api := API{Silencer: NewSilencer(), Ingest: ingest}
+			server := httptest.NewServer(api.Handler())
+			defer server.Close()
+
+			received := make(chan []LogLine)
+			go func() {
+				var lines []LogLine
+				for line := range ingest.Lines() {
+					lines = append(lines, line)
+					if len(lines) == len(c.ExpectedSections) {
+						break
+					}
+				}
+				received <- lines
+			}()
+
+			body := []byte(c.Body)
+			if c.Gzip {
+				var compressed bytes.Buffer
+				writer := gzip.NewWriter(&compressed)
+				_, err := writer.Write(body)
+				if err != nil {
+					t.Fatal(err)
+				}
+				writer.Close()
+				body = compressed.Bytes()
+			}
+
+			req, err := http.NewRequest(http.MethodPost, server.URL+"/api/ingest?source=web", bytes.NewReader(body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Authorization", "Bearer "+c.Token)
+			if c.NoScheme {
+				req.Header.Set("Authorization", c.Token)
+			}
+			req.Header.Set("Content-Type", c.ContentType)
+			if c.Gzip {
+				req.Header.Set("Content-Encoding", "gzip")
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != c.ExpectedStatus {
+				t.Fatal("unexpected status", "expected", c.ExpectedStatus, "actual", resp.StatusCode)
+			}
+			if resp.StatusCode != http.StatusOK {
+				return
+			}
+
+			var result IngestResult
+			err = json.NewDecoder(resp.Body).Decode(&result)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(c.ExpectedResult, result) {
+				t.Fatal("unexpected result", "expected", c.ExpectedResult, "actual", result)
+			}
+			lock.Lock()
+			if !reflect.DeepEqual(c.ExpectedRejected, rejected) {
+				t.Fatal("unexpected rejected lines", "expected", c.ExpectedRejected, "actual", rejected)
+			}
+			lock.Unlock()
+
+			var sections []string
+			for _, line := range <-received {
+				if line.Source != "web" || line.Event == nil || line.Event.Source != "web" {
+					t.Fatal("unexpected line", "expected", "event of web", "actual", line)
+				}
+				sections = append(sections, line.Event.Section)
+			}
+			if !reflect.DeepEqual(c.ExpectedSections, sections) {
+				t.Fatal("unexpected sections", "expected", c.ExpectedSections, "actual", sections)
+			}
+		})
+	}
+}
+
+func TestIngestConfig_String(t *testing.T) {
+	config := Configuration{Ingest: IngestConfig{Tokens: []string{"s3cr3t", "0th3r"}, MaxBodySize: 1024}}
+
+	description := fmt.Sprint(config)
+	if strings.Contains(description, "s3cr3t") || strings.Contains(description, "0th3r") {
+		t.Fatal("unexpected tokens in the configuration", "actual", description)
+	}
+	if len(config.Ingest.Tokens) != 2 || config.Ingest.Tokens[0] != "s3cr3t" {
+		t.Fatal("unexpected tokens modified", "actual", config.Ingest.Tokens)
+	}
+}
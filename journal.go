@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Journal records the alert transitions in an append-only file of JSON lines
+type Journal struct {
+	sync.Mutex
+	path string
+	file *os.File
+}
+
+// JournalFilter selects the alerts read from the journal, zero values match all the alerts
+type JournalFilter struct {
+	Name  string
+	Scope string
+	Key   string
+	State AlertState
+	Since time.Time
+	Until time.Time
+}
+
+// AlertDuration is the time spent in alert by an alert identity
+type AlertDuration struct {
+	Name     string        `json:"name"`
+	Scope    string        `json:"scope"`
+	Key      string        `json:"key"`
+	Duration time.Duration `json:"duration"`
+	Count    int           `json:"count"` // Number of times the alert fired
+}
+
+func OpenJournal(path string) (*Journal, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open journal: %w", err)
+	}
+
+	return &Journal{path: path, file: file}, nil
+}
+
+// Record appends the alert at the end of the journal
+func (j *Journal) Record(alert Alert) error {
+	raw, err := json.Marshal(alert)
+	if err != nil {
+		return fmt.Errorf("cannot encode alert: %w", err)
+	}
+
+	j.Lock()
+	defer j.Unlock()
+
+	_, err = j.file.Write(append(raw, '\n'))
+	return err
+}
+
+// Alerts reads the alerts of the journal matching the filter, in the order they were recorded
+func (j *Journal) Alerts(filter JournalFilter) ([]Alert, error) {
+	// avoids to read a line being written
+	j.Lock()
+	defer j.Unlock()
+
+	file, err := os.Open(j.path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open journal: %w", err)
+	}
+	defer file.Close()
+
+	var alerts []Alert
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var alert Alert
+		err := json.Unmarshal(scanner.Bytes(), &alert)
+		if err != nil {
+			return nil, fmt.Errorf("cannot decode journal line %q: %w", scanner.Text(), err)
+		}
+
+		if filter.match(alert) {
+			alerts = append(alerts, alert)
+		}
+	}
+
+	return alerts, scanner.Err()
+}
+
+// Close closes the journal file
+func (j *Journal) Close() error {
+	j.Lock()
+	defer j.Unlock()
+
+	return j.file.Close()
+}
+
+func (f JournalFilter) match(alert Alert) bool {
+	switch {
+	case len(f.Name) > 0 && f.Name != alert.Name:
+		return false
+	case len(f.Scope) > 0 && f.Scope != alert.Scope:
+		return false
+	case len(f.Key) > 0 && f.Key != alert.Key:
+		return false
+	case len(f.State) > 0 && f.State != alert.State:
+		return false
+	case !f.Since.IsZero() && alert.TriggeredAt.Before(f.Since):
+		return false
+	case !f.Until.IsZero() && alert.TriggeredAt.After(f.Until):
+		return false
+	}
+
+	return true
+}
+
+// TimeInAlert computes the time spent firing by each alert identity from the transitions of the journal,
+// the alerts still firing are counted until the end time
+func TimeInAlert(alerts []Alert, until time.Time) []AlertDuration {
+	durations := make(map[string]*AlertDuration)
+	firingSince := make(map[string]time.Time)
+
+	for _, alert := range alerts {
+		id := alert.Fingerprint()
+		duration, ok := durations[id]
+		if !ok {
+			duration = &AlertDuration{Name: alert.Name, Scope: alert.Scope, Key: alert.Key}
+			durations[id] = duration
+		}
+
+		switch alert.State {
+		case AlertFiring:
+			if _, ok := firingSince[id]; !ok {
+				firingSince[id] = alert.TriggeredAt
+				duration.Count++
+			}
+		case AlertResolved:
+			if since, ok := firingSince[id]; ok {
+				duration.Duration += alert.TriggeredAt.Sub(since)
+				delete(firingSince, id)
+			}
+		}
+	}
+
+	for id, since := range firingSince {
+		if until.After(since) {
+			durations[id].Duration += until.Sub(since)
+		}
+	}
+
+	result := make([]AlertDuration, 0, len(durations))
+	for _, duration := range durations {
+		if duration.Count > 0 {
+			result = append(result, *duration)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Duration == result[j].Duration {
+			return result[i].Name+result[i].Scope+result[i].Key < result[j].Name+result[j].Scope+result[j].Key
+		}
+		return result[i].Duration > result[j].Duration
+	})
+
+	return result
+}
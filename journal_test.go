@@ -0,0 +1,109 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestJournal_Alerts(t *testing.T) {
+	journal, cleanup := setupJournal(t)
+	defer cleanup()
+
+	start := time.Date(2020, 02, 24, 2, 0, 0, 0, time.UTC)
+	alerts := []Alert{
+		{Name: HighTrafficAlert, State: AlertFiring, Hits: 12000, AverageRate: 200, Threshold: 100, ActiveAt: start, TriggeredAt: start},
+		{Name: HighTrafficAlert, Scope: SectionScope, Key: "login", State: AlertFiring, Hits: 6000, AverageRate: 100, Threshold: 50, ActiveAt: start, TriggeredAt: start.Add(time.Minute)},
+		{Name: HighTrafficAlert, State: AlertResolved, Hits: 600, AverageRate: 10, Threshold: 100, ActiveAt: start, TriggeredAt: start.Add(10 * time.Minute)},
+		{Name: HighTrafficAlert, State: AlertFiring, Hits: 18000, AverageRate: 300, Threshold: 100, ActiveAt: start.Add(time.Hour), TriggeredAt: start.Add(time.Hour)},
+	}
+	for _, alert := range alerts {
+		err := journal.Record(alert)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	type testCase struct {
+		Filter   JournalFilter
+		Expected []Alert
+	}
+
+	cases := map[string]testCase{
+		"all alerts": {
+			Filter:   JournalFilter{},
+			Expected: alerts,
+		},
+		"by section": {
+			Filter:   JournalFilter{Scope: SectionScope, Key: "login"},
+			Expected: alerts[1:2],
+		},
+		"by state": {
+			Filter:   JournalFilter{State: AlertResolved},
+			Expected: alerts[2:3],
+		},
+		"by time range": {
+			Filter:   JournalFilter{Since: start.Add(time.Minute), Until: start.Add(10 * time.Minute)},
+			Expected: alerts[1:3],
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			actual, err := journal.Alerts(c.Filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(c.Expected, actual) {
+				t.Fatal("unexpected alerts", "expected", c.Expected, "actual", actual)
+			}
+		})
+	}
+}
+
+func TestTimeInAlert(t *testing.T) {
+	start := time.Date(2020, 02, 24, 2, 0, 0, 0, time.UTC)
+	alerts := []Alert{
+		{Name: HighTrafficAlert, State: AlertPending, TriggeredAt: start},
+		{Name: HighTrafficAlert, State: AlertFiring, TriggeredAt: start.Add(2 * time.Minute)},
+		{Name: HighTrafficAlert, Scope: SectionScope, Key: "login", State: AlertFiring, TriggeredAt: start.Add(5 * time.Minute)},
+		{Name: HighTrafficAlert, State: AlertResolved, TriggeredAt: start.Add(12 * time.Minute)},
+		{Name: HighTrafficAlert, State: AlertFiring, TriggeredAt: start.Add(30 * time.Minute)},
+		{Name: HighTrafficAlert, State: AlertResolved, TriggeredAt: start.Add(35 * time.Minute)},
+		{Name: NoDataAlert, State: AlertPending, TriggeredAt: start.Add(40 * time.Minute)},
+		{Name: NoDataAlert, State: AlertResolved, TriggeredAt: start.Add(41 * time.Minute)},
+	}
+
+	// the section alert is still firing
+	actual := TimeInAlert(alerts, start.Add(time.Hour))
+
+	expected := []AlertDuration{
+		{Name: HighTrafficAlert, Scope: SectionScope, Key: "login", Duration: 55 * time.Minute, Count: 1},
+		{Name: HighTrafficAlert, Duration: 15 * time.Minute, Count: 2},
+	}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatal("unexpected time in alert", "expected", expected, "actual", actual)
+	}
+}
+
+// setupJournal opens a journal in a temporary file, the returned function closes and removes it
+func setupJournal(t *testing.T) (*Journal, func()) {
+	file, err := ioutil.TempFile("", "journal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	journal, err := OpenJournal(file.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return journal, func() {
+		journal.Close()
+		os.Remove(file.Name())
+	}
+}
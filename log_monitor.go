@@ -2,6 +2,7 @@ package main
 
 import (
 	"sort"
+	"sync"
 	"time"
 
 	"github.com/ali.ghanem/http-log-monitoring/commonlog"
@@ -10,104 +11,616 @@ import (
 
 // Service to monitor a log file in the W3C common format
 type LogMonitor struct {
+	// Clock tells the time of the checks: the wall clock or the time of the events for the historical logs
+	Clock metric.Clock
+
 	// Sections contains the number of hits by section visited
 	Sections *metric.CounterVec
 
 	// HitsSeries stores the number of hits by bucket of time
 	HitsSeries *metric.TimeSeries
 
+	// SectionHitsSeries stores the number of hits by section and bucket of time
+	SectionHitsSeries *metric.TimeSeriesVec
+
+	// HostHitsSeries stores the number of hits by client host and bucket of time
+	HostHitsSeries *metric.TimeSeriesVec
+
+	// TrackHosts enables the hits by client host, they are only counted for the host traffic thresholds
+	// as a series is kept for each client
+	TrackHosts bool
+
+	// Backends contains the number of hits by backend of the proxy logs
+	Backends *metric.CounterVec
+
+	// TerminationHitsSeries stores the number of sessions which did not terminate normally
+	// by termination state of the proxy logs and bucket of time
+	TerminationHitsSeries *metric.TimeSeriesVec
+
 	// Last alert occurred during monitoring
 	LastAlert *Alert
 
+	// Last low traffic alert occurred during monitoring
+	LastLowTrafficAlert *Alert
+
+	// LowTrafficCheckedSince is the time of the first low traffic check,
+	// the traffic is evaluated once a full period has been observed
+	LowTrafficCheckedSince time.Time
+
+	// Last alert about the absence of data in the log
+	LastNoDataAlert *Alert
+
+	// Last alerts occurred on a section or a host, identified by scope and key
+	LastScopedAlerts map[string]*Alert
+
+	// Last burn rate alerts occurred on the SLOs, identified by rule name and section
+	LastSLOAlerts map[string]*Alert
+
+	// Last alerts occurred on the apdex score of a section, identified by section
+	LastApdexAlerts map[string]*Alert
+
+	// Apdex classifies the requests of the sections by their duration
+	Apdex *Apdex
+
+	// StatsInterval is the interval of the statistics computed on the recent requests such as the apdex scores
+	StatsInterval time.Duration
+
+	// SLOs are the availability objectives by section
+	SLOs map[string]*SLO
+
+	// SLOWindow is the rolling window of the error budgets
+	SLOWindow time.Duration
+
+	// LastLineReceivedAt is the time the last line was read from the log
+	LastLineReceivedAt time.Time
+
+	// PendingDuration is the time a rule condition must be met before its alert fires
+	PendingDuration time.Duration
+
+	// Ingestion is the queue of the lines waiting to be handled, nil when the lines are not queued
+	Ingestion IngestionQueue
+
+	// Rejected contains the number of lines rejected by reason, such as the lines which cannot be parsed
+	Rejected *metric.CounterVec
+
 	// Metrics
 	Calls *metric.CounterVec
 	Bytes *metric.Counter
-}
 
-// Represents a traffic alert
-type Alert struct {
-	Exceed      bool
-	Hits        int64
-	AverageRate int64
-	TriggeredAt time.Time
+	// Sources contains the traffic counters by log source
+	Sources      map[string]*SourceCounters
+	sourcesMutex sync.RWMutex
 }
 
 // Statistics about traffic
 type Statistics struct {
-	TopSections  []Section
-	HitsByStatus map[string]int64
-	TotalBytes   int64
+	TopSections   []Section        `json:"top_sections"`
+	HitsByStatus  map[string]int64 `json:"hits_by_status"`
+	TotalBytes    int64            `json:"total_bytes"`
+	ErrorBudgets  []ErrorBudget    `json:"error_budgets,omitempty"`
+	Apdex         []ApdexScore     `json:"apdex,omitempty"`
+	HitsBySource  map[string]int64 `json:"hits_by_source,omitempty"`
+	HitsByBackend map[string]int64 `json:"hits_by_backend,omitempty"`
+	Ingestion     *Ingestion       `json:"ingestion,omitempty"`
+
+	RejectedByReason map[string]int64 `json:"rejected_by_reason,omitempty"`
+}
+
+// IngestionQueue is the queue of the lines read and not handled yet by the monitor
+type IngestionQueue interface {
+	Depth() int
+	Capacity() int
+	Dropped() int64
+	Sampled() int64
+}
+
+// Ingestion describes the load of the queue of the lines and the lines shed by its overflow policy
+type Ingestion struct {
+	QueueDepth    int   `json:"queue_depth"`
+	QueueCapacity int   `json:"queue_capacity"`
+	DroppedLines  int64 `json:"dropped_lines"`
+	SampledLines  int64 `json:"sampled_lines"`
+}
+
+// SourceCounters counts the traffic of a single log source
+type SourceCounters struct {
+	Sections *metric.CounterVec
+	Calls    *metric.CounterVec
+	Bytes    *metric.Counter
 }
 
 // Section visited
 type Section struct {
-	Name string
-	Hits int64
+	Name string `json:"name"`
+	Hits int64  `json:"hits"`
+}
+
+// NewLogMonitor creates a monitor with empty metrics following the wall clock
+func NewLogMonitor() *LogMonitor {
+	return NewLogMonitorWithClock(metric.SystemClock)
+}
+
+// NewLogMonitorWithClock creates a monitor with empty metrics, its alerts and statistics follow the clock
+func NewLogMonitorWithClock(clock metric.Clock) *LogMonitor {
+	return &LogMonitor{
+		Clock:                 clock,
+		Sections:              metric.NewCounterVec(),
+		HitsSeries:            metric.NewTimeSeriesWithClock(clock),
+		SectionHitsSeries:     metric.NewTimeSeriesVecWithClock(clock),
+		HostHitsSeries:        metric.NewTimeSeriesVecWithClock(clock),
+		Backends:              metric.NewCounterVec(),
+		Rejected:              metric.NewCounterVec(),
+		TerminationHitsSeries: metric.NewTimeSeriesVecWithClock(clock),
+		LastScopedAlerts:      make(map[string]*Alert),
+		LastSLOAlerts:         make(map[string]*Alert),
+		LastApdexAlerts:       make(map[string]*Alert),
+		Apdex:                 NewApdex(make(map[string]time.Duration), clock),
+		StatsInterval:         time.Minute,
+		SLOs:                  make(map[string]*SLO),
+		SLOWindow:             DefaultSLOWindow,
+		Calls:                 metric.NewCounterVec(),
+		Bytes:                 metric.NewCounter(),
+		Sources:               make(map[string]*SourceCounters),
+	}
+}
+
+// LineRejected counts a line rejected for the reason, such as the reason of its parse error
+func (l *LogMonitor) LineRejected(reason string) {
+	l.Rejected.Inc(reason, 1)
 }
 
 // HandleEvent manages the log event by the monitor
 func (l *LogMonitor) HandleEvent(event commonlog.Event) {
-	l.Calls.Inc(Total, 1)
+	l.HandleSampledEvent(event, 1)
+}
+
+// HandleSampledEvent manages a log event standing for weight events, the ones skipped by the sampling of the lines,
+// so the counters are scaled up to the traffic of the log
+func (l *LogMonitor) HandleSampledEvent(event commonlog.Event, weight int64) {
+	countTraffic(l.Sections, l.Calls, l.Bytes, event, weight)
+	if len(event.Source) > 0 {
+		source := l.source(event.Source)
+		countTraffic(source.Sections, source.Calls, source.Bytes, event, weight)
+	}
+
+	l.HitsSeries.Inc(event.Date, weight)
+	l.SectionHitsSeries.Inc(event.Section, event.Date, weight)
+	if l.TrackHosts {
+		l.HostHitsSeries.Inc(event.Host, event.Date, weight)
+	}
+
+	if backend, ok := event.Fields[commonlog.BackendField]; ok {
+		l.Backends.Inc(backend, weight)
+	}
+	if state, ok := terminationState(event); ok {
+		l.TerminationHitsSeries.Inc(state, event.Date, weight)
+	}
+
+	if slo, ok := l.SLOs[event.Section]; ok {
+		slo.Record(event, weight)
+	}
+
+	l.Apdex.Record(event, weight)
+}
+
+// countTraffic counts the hits of the event by status and by section and the bytes sent
+func countTraffic(sections *metric.CounterVec, calls *metric.CounterVec, bytes *metric.Counter, event commonlog.Event, weight int64) {
+	calls.Inc(Total, weight)
 	switch {
 	case IsInformational(event.Status):
-		l.Calls.Inc(Info, 1)
+		calls.Inc(Info, weight)
 	case IsSuccess(event.Status):
-		l.Calls.Inc(Succeed, 1)
+		calls.Inc(Succeed, weight)
 	case IsRedirection(event.Status):
-		l.Calls.Inc(Redirected, 1)
+		calls.Inc(Redirected, weight)
 	case IsClientError(event.Status):
-		l.Calls.Inc(ClientError, 1)
+		calls.Inc(ClientError, weight)
 	case IsServerError(event.Status):
-		l.Calls.Inc(ServerError, 1)
+		calls.Inc(ServerError, weight)
 	default:
-		l.Calls.Inc(Unknown, 1)
+		calls.Inc(Unknown, weight)
+	}
+
+	bytes.Inc(int64(event.Bytes) * weight)
+
+	sections.Inc(event.Section, weight)
+}
+
+// terminationState returns the first two characters of the termination state of a proxy session:
+// who ended the session and at which step. It returns false for the sessions terminated normally.
+func terminationState(event commonlog.Event) (string, bool) {
+	state := event.Fields[commonlog.TerminationStateField]
+	if len(state) < 2 || state[:2] == "--" {
+		return "", false
 	}
+	return state[:2], true
+}
 
-	l.Bytes.Inc(int64(event.Bytes))
+// source returns the counters of a log source, they are created on its first event
+func (l *LogMonitor) source(name string) *SourceCounters {
+	l.sourcesMutex.Lock()
+	defer l.sourcesMutex.Unlock()
 
-	l.HitsSeries.Inc(event.Date, 1)
+	source, ok := l.Sources[name]
+	if !ok {
+		source = &SourceCounters{
+			Sections: metric.NewCounterVec(),
+			Calls:    metric.NewCounterVec(),
+			Bytes:    metric.NewCounter(),
+		}
+		l.Sources[name] = source
+	}
+	return source
+}
+
+// AddSLO tracks an availability objective for a section
+func (l *LogMonitor) AddSLO(section string, objective float64) {
+	l.SLOs[section] = NewSLO(section, objective, l.Clock)
+}
 
-	l.Sections.Inc(event.Section, 1)
+// LineReceived records that a line has been read from the log
+func (l *LogMonitor) LineReceived(at time.Time) {
+	l.LastLineReceivedAt = at
 }
 
 // CheckTrafficLoad check the traffic and may return an alert about important change on the load
 func (l *LogMonitor) CheckTrafficLoad(hits int64, interval time.Duration, threshold int64) *Alert {
 	avgRate := hits / int64(interval.Seconds())
 
-	if avgRate >= threshold {
-		// exceeds the threshold
-		if l.LastAlert != nil && l.LastAlert.Hits == hits {
-			// same number of hits => return the original alert
-			return l.LastAlert
+	candidate := Alert{Name: HighTrafficAlert, Hits: hits, AverageRate: avgRate, Threshold: threshold}
+	return l.checkAlert(&l.LastAlert, candidate, avgRate >= threshold)
+}
+
+// CheckTrafficDrop check the traffic and may return an alert when the load falls below the threshold.
+// The traffic is not evaluated before a full interval after the first check, as the hits of the period are incomplete.
+func (l *LogMonitor) CheckTrafficDrop(hits int64, interval time.Duration, threshold int64) *Alert {
+	now := l.Clock.Now()
+	if l.LowTrafficCheckedSince.IsZero() {
+		l.LowTrafficCheckedSince = now
+	}
+	if now.Sub(l.LowTrafficCheckedSince) < interval {
+		return nil
+	}
+
+	avgRate := hits / int64(interval.Seconds())
+
+	candidate := Alert{Name: LowTrafficAlert, Hits: hits, AverageRate: avgRate, Threshold: threshold}
+	return l.checkAlert(&l.LastLowTrafficAlert, candidate, avgRate < threshold)
+}
+
+// CheckNoData may return an alert when no line has been read from the log since the timeout
+func (l *LogMonitor) CheckNoData(timeout time.Duration) *Alert {
+	silent := l.Clock.Now().Sub(l.LastLineReceivedAt) >= timeout
+
+	candidate := Alert{Name: NoDataAlert, Threshold: int64(timeout.Seconds())}
+	return l.checkAlert(&l.LastNoDataAlert, candidate, silent)
+}
+
+// CheckScopedTrafficLoad checks the traffic of each key of a scope (sections or hosts)
+// against its threshold and returns the alerts about important changes on their load.
+// The threshold of the key "*" applies to all the keys without their own threshold.
+func (l *LogMonitor) CheckScopedTrafficLoad(scope string, hits map[string]int64, interval time.Duration, thresholds map[string]int64) []*Alert {
+	var alerts []*Alert
+	for _, key := range l.scopedKeys(scope, hits, thresholds) {
+		threshold, _ := scopedThreshold(thresholds, key)
+		avgRate := hits[key] / int64(interval.Seconds())
+
+		candidate := Alert{Name: HighTrafficAlert, Scope: scope, Key: key, Hits: hits[key], AverageRate: avgRate, Threshold: threshold}
+		if alert := l.checkScopedAlert(candidate, avgRate >= threshold); alert != nil {
+			alerts = append(alerts, alert)
+		}
+	}
+
+	return alerts
+}
+
+// CheckTerminationStates checks the number of proxy sessions which ended with each termination state
+// over the interval against its threshold and returns the alerts when there are too many of them.
+// The threshold of the key "*" applies to all the termination states without their own threshold.
+func (l *LogMonitor) CheckTerminationStates(hits map[string]int64, interval time.Duration, thresholds map[string]int64) []*Alert {
+	var alerts []*Alert
+	for _, key := range l.scopedKeys(TerminationScope, hits, thresholds) {
+		threshold, _ := scopedThreshold(thresholds, key)
+
+		candidate := Alert{
+			Name:        TerminationStateAlert,
+			Scope:       TerminationScope,
+			Key:         key,
+			Hits:        hits[key],
+			AverageRate: hits[key] / int64(interval.Seconds()),
+			Threshold:   threshold,
+		}
+		if alert := l.checkScopedAlert(candidate, hits[key] >= threshold); alert != nil {
+			alerts = append(alerts, alert)
 		}
+	}
+
+	return alerts
+}
 
-		l.LastAlert = &Alert{
-			Exceed:      true,
-			Hits:        hits,
-			AverageRate: avgRate,
-			TriggeredAt: time.Now(),
+// scopedKeys returns the sorted keys of the scope to check: the ones with a threshold
+// and the ones with an ongoing alert to detect the back to normal
+func (l *LogMonitor) scopedKeys(scope string, hits map[string]int64, thresholds map[string]int64) []string {
+	keys := make(map[string]bool)
+	for key := range hits {
+		if _, ok := scopedThreshold(thresholds, key); ok {
+			keys[key] = true
+		}
+	}
+	for _, alert := range l.LastScopedAlerts {
+		if alert.Scope == scope {
+			keys[alert.Key] = true
 		}
-		return l.LastAlert
 	}
 
-	if l.LastAlert == nil {
-		// no previous alert no need to return an alert to say that the traffic came back to normal
-		return nil
+	sorted := make([]string, 0, len(keys))
+	for key := range keys {
+		sorted = append(sorted, key)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
+
+// checkScopedAlert updates the last alert of the scope and key of the candidate according to the condition
+func (l *LogMonitor) checkScopedAlert(candidate Alert, active bool) *Alert {
+	id := candidate.Scope + "|" + candidate.Key
+	last := l.LastScopedAlerts[id]
+	alert := l.checkAlert(&last, candidate, active)
+	if last == nil {
+		delete(l.LastScopedAlerts, id)
+	} else {
+		l.LastScopedAlerts[id] = last
+	}
+	return alert
+}
+
+// CheckBurnRates checks how fast the error budget of each SLO is consumed and returns the alerts
+// of the multi-window burn rate rules: the burn rate must exceed the threshold on both windows
+func (l *LogMonitor) CheckBurnRates() []*Alert {
+	sections := make([]string, 0, len(l.SLOs))
+	for section := range l.SLOs {
+		sections = append(sections, section)
+	}
+	sort.Strings(sections)
+
+	now := l.Clock.Now()
+	var alerts []*Alert
+	for _, section := range sections {
+		slo := l.SLOs[section]
+		for _, rule := range burnRateRules {
+			since := now.Add(-1 * rule.LongWindow)
+			longBurnRate := slo.BurnRate(since)
+			shortBurnRate := slo.BurnRate(now.Add(-1 * rule.ShortWindow))
+
+			hits := slo.Total.CountSince(since.Truncate(sloBucket))
+			id := rule.Name + "|" + section
+			last := l.LastSLOAlerts[id]
+			candidate := Alert{
+				Name:           rule.Name,
+				Scope:          SectionScope,
+				Key:            section,
+				Hits:           hits,
+				AverageRate:    hits / int64(rule.LongWindow.Seconds()),
+				Value:          longBurnRate,
+				ValueThreshold: rule.Threshold,
+			}
+			alert := l.checkAlert(&last, candidate, longBurnRate >= rule.Threshold && shortBurnRate >= rule.Threshold)
+			if last == nil {
+				delete(l.LastSLOAlerts, id)
+			} else {
+				l.LastSLOAlerts[id] = last
+			}
+
+			if alert != nil {
+				alerts = append(alerts, alert)
+			}
+		}
+	}
+
+	return alerts
+}
+
+// CheckApdex checks the apdex score of the sections over the interval against their threshold
+// and returns the alerts when the score falls below it.
+// The threshold of the key "*" applies to all the sections without their own threshold.
+func (l *LogMonitor) CheckApdex(interval time.Duration, thresholds map[string]float64) []*Alert {
+	scores := make(map[string]ApdexScore)
+	for _, score := range l.Apdex.Scores(l.Clock.Now().Add(-1 * interval)) {
+		scores[score.Section] = score
+	}
+
+	// check the sections with a threshold and the ones with an ongoing alert to detect the back to normal
+	sections := make(map[string]bool)
+	for section := range scores {
+		if _, ok := apdexThreshold(thresholds, section); ok {
+			sections[section] = true
+		}
+	}
+	for section := range l.LastApdexAlerts {
+		sections[section] = true
+	}
+
+	sorted := make([]string, 0, len(sections))
+	for section := range sections {
+		sorted = append(sorted, section)
+	}
+	sort.Strings(sorted)
+
+	var alerts []*Alert
+	for _, section := range sorted {
+		threshold, _ := apdexThreshold(thresholds, section)
+		score, ok := scores[section]
+
+		last := l.LastApdexAlerts[section]
+		candidate := Alert{
+			Name:           LowApdexAlert,
+			Scope:          SectionScope,
+			Key:            section,
+			Hits:           score.Total,
+			AverageRate:    score.Total / int64(interval.Seconds()),
+			Value:          score.Score,
+			ValueThreshold: threshold,
+		}
+		// no request during the interval is not a bad score
+		alert := l.checkAlert(&last, candidate, ok && score.Score < threshold)
+		if last == nil {
+			delete(l.LastApdexAlerts, section)
+		} else {
+			l.LastApdexAlerts[section] = last
+		}
+
+		if alert != nil {
+			alerts = append(alerts, alert)
+		}
 	}
 
-	// lower than the threshold => back to normal no more alert to follow
-	l.LastAlert = nil
-	return &Alert{
-		Exceed:      false,
-		Hits:        hits,
-		AverageRate: avgRate,
-		TriggeredAt: time.Now(),
+	return alerts
+}
+
+// apdexThreshold returns the apdex threshold of the section or the default one
+func apdexThreshold(thresholds map[string]float64, section string) (float64, bool) {
+	if threshold, ok := thresholds[section]; ok {
+		return threshold, true
 	}
+
+	threshold, ok := thresholds[AnyKey]
+	return threshold, ok
+}
+
+// scopedThreshold returns the threshold of the key or the default one
+func scopedThreshold(thresholds map[string]int64, key string) (int64, bool) {
+	if threshold, ok := thresholds[key]; ok {
+		return threshold, true
+	}
+
+	threshold, ok := thresholds[AnyKey]
+	return threshold, ok
+}
+
+// checkAlert updates the last alert of a rule according to its condition
+// and returns the alert to display if any.
+// While the condition is met, the ongoing alert is updated with the values of the check
+// so it keeps its fingerprint and the time it started.
+func (l *LogMonitor) checkAlert(last **Alert, candidate Alert, active bool) *Alert {
+	now := l.Clock.Now()
+	previous := *last
+
+	if !active {
+		if previous == nil {
+			// no previous alert no need to return an alert to say that the traffic came back to normal
+			return nil
+		}
+
+		// back to normal no more alert to follow
+		*last = nil
+		candidate.State = AlertResolved
+		candidate.ActiveAt = previous.ActiveAt
+		candidate.StartsAt = previous.StartsAt
+		candidate.TriggeredAt = now
+		return &candidate
+	}
+
+	if previous == nil {
+		// the condition starts to be met
+		candidate.State = AlertPending
+		candidate.ActiveAt = now
+		if l.PendingDuration == 0 {
+			candidate.State = AlertFiring
+			candidate.StartsAt = now
+		}
+		candidate.TriggeredAt = now
+		*last = &candidate
+		return *last
+	}
+
+	if previous.State == AlertPending && now.Sub(previous.ActiveAt) >= l.PendingDuration {
+		// the condition has been met long enough
+		previous.State = AlertFiring
+		previous.StartsAt = now
+	}
+	previous.Hits = candidate.Hits
+	previous.AverageRate = candidate.AverageRate
+	previous.Threshold = candidate.Threshold
+	previous.Value = candidate.Value
+	previous.ValueThreshold = candidate.ValueThreshold
+	previous.TriggeredAt = now
+	return previous
 }
 
 // Statistics returns statistics about the traffic generated
 func (l *LogMonitor) Statistics(maxSections int) Statistics {
-	sections := l.Sections.AllValues()
+	since := l.Clock.Now().Add(-1 * l.SLOWindow)
+	var budgets []ErrorBudget
+	for _, slo := range l.SLOs {
+		budgets = append(budgets, slo.Budget(since))
+	}
+	sort.Slice(budgets, func(i, j int) bool {
+		return budgets[i].Section < budgets[j].Section
+	})
+
+	var hitsBySource map[string]int64
+	l.sourcesMutex.RLock()
+	for name, source := range l.Sources {
+		if hitsBySource == nil {
+			hitsBySource = make(map[string]int64)
+		}
+		hitsBySource[name] = source.Calls.Value(Total)
+	}
+	l.sourcesMutex.RUnlock()
+
+	var hitsByBackend map[string]int64
+	if backends := l.Backends.AllValues(); len(backends) > 0 {
+		hitsByBackend = backends
+	}
+
+	var rejectedByReason map[string]int64
+	if rejected := l.Rejected.AllValues(); len(rejected) > 0 {
+		rejectedByReason = rejected
+	}
+
+	var ingestion *Ingestion
+	if l.Ingestion != nil {
+		ingestion = &Ingestion{
+			QueueDepth:    l.Ingestion.Depth(),
+			QueueCapacity: l.Ingestion.Capacity(),
+			DroppedLines:  l.Ingestion.Dropped(),
+			SampledLines:  l.Ingestion.Sampled(),
+		}
+	}
+
+	return Statistics{
+		TopSections:   topSections(l.Sections, maxSections),
+		HitsByStatus:  l.Calls.AllValues(),
+		TotalBytes:    l.Bytes.Value(),
+		ErrorBudgets:  budgets,
+		Apdex:         l.Apdex.Scores(l.Clock.Now().Add(-1 * l.StatsInterval)),
+		HitsBySource:  hitsBySource,
+		HitsByBackend: hitsByBackend,
+		Ingestion:     ingestion,
+
+		RejectedByReason: rejectedByReason,
+	}
+}
+
+// SourceStatistics returns statistics about the traffic of a single log source
+func (l *LogMonitor) SourceStatistics(name string, maxSections int) (Statistics, bool) {
+	l.sourcesMutex.RLock()
+	source, ok := l.Sources[name]
+	l.sourcesMutex.RUnlock()
+	if !ok {
+		return Statistics{}, false
+	}
+
+	return Statistics{
+		TopSections:  topSections(source.Sections, maxSections),
+		HitsByStatus: source.Calls.AllValues(),
+		TotalBytes:   source.Bytes.Value(),
+	}, true
+}
+
+// topSections returns the sections with maximum hits
+func topSections(counters *metric.CounterVec, maxSections int) []Section {
+	sections := counters.AllValues()
 	// sort the sections
 	cs := make([]Section, len(sections))
 	i := 0
@@ -123,11 +636,7 @@ func (l *LogMonitor) Statistics(maxSections int) Statistics {
 		cs = cs[0:maxSections]
 	}
 
-	return Statistics{
-		TopSections:  cs,
-		HitsByStatus: l.Calls.AllValues(),
-		TotalBytes:   l.Bytes.Value(),
-	}
+	return cs
 }
 
 // HTTP status category
@@ -2,16 +2,17 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
 	"time"
 
 	"github.com/ali.ghanem/http-log-monitoring/commonlog"
 	"github.com/ali.ghanem/http-log-monitoring/metric"
-	"github.com/hpcloud/tail"
 )
 
 func main() {
@@ -37,6 +38,14 @@ func main() {
 
 	log.Println("configuration read", config)
 
+	if config.Mode == BatchMode || config.Mode == ReplayMode {
+		err := runBatch(config)
+		if err != nil {
+			log.Fatal(fmt.Sprintf("cannot analyze logs - err: %s", err))
+		}
+		return
+	}
+
 	var statsTicker = time.NewTicker(config.StatsDisplayInterval)
 	var alertingTicker = time.NewTicker(config.TrafficLoadCheckInterval)
 	var cleaningTimeSeriesTicker = time.NewTicker(config.CleaningInterval)
@@ -44,18 +53,172 @@ func main() {
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt)
 
-	var monitor = LogMonitor{
-		Sections:   metric.NewCounterVec(),
-		HitsSeries: metric.NewTimeSeries(),
-		Calls:      metric.NewCounterVec(),
-		Bytes:      metric.NewCounter(),
+	var monitor = newMonitor(config, metric.SystemClock)
+
+	var notifiers MultiNotifier
+	if len(config.Webhook.URL) > 0 {
+		webhook, err := NewWebhookNotifier(config.Webhook)
+		if err != nil {
+			log.Fatal(fmt.Sprintf("cannot create webhook notifier - err: %s", err))
+		}
+		notifiers = append(notifiers, webhook)
+	}
+	if len(config.Alertmanager.URL) > 0 {
+		alertmanager, err := NewAlertmanagerNotifier(config.Alertmanager)
+		if err != nil {
+			log.Fatal(fmt.Sprintf("cannot create alertmanager notifier - err: %s", err))
+		}
+		notifiers = append(notifiers, alertmanager)
+	}
+	if len(config.SMTP.Address) > 0 {
+		smtpNotifier, err := NewSMTPNotifier(config.SMTP)
+		if err != nil {
+			log.Fatal(fmt.Sprintf("cannot create smtp notifier - err: %s", err))
+		}
+		notifiers = append(notifiers, smtpNotifier)
+	}
+	if len(config.Exec.Command) > 0 {
+		execNotifier, err := NewExecNotifier(config.Exec)
+		if err != nil {
+			log.Fatal(fmt.Sprintf("cannot create exec notifier - err: %s", err))
+		}
+		notifiers = append(notifiers, execNotifier)
+	}
+
+	var silencer = NewSilencer()
+	if len(config.SilencesFile) > 0 {
+		silencer, err = LoadSilencer(config.SilencesFile)
+		if err != nil {
+			log.Fatal(fmt.Sprintf("cannot load silences - err: %s", err))
+		}
+	}
+
+	var notifier = NewSilencingNotifier(silencer, notifiers)
+	defer func() {
+		err := notifier.Close()
+		if err != nil {
+			log.Println("failed to close notifiers", "err", err)
+		}
+	}()
+
+	var journal *Journal
+	if len(config.AlertJournalFile) > 0 {
+		journal, err = OpenJournal(config.AlertJournalFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer func() {
+			err := journal.Close()
+			if err != nil {
+				log.Println("failed to close journal", "err", err)
+			}
+		}()
+	}
+	var reporter = newAlertReporter(notifier, journal, config.AlertRepeatInterval)
+
+	deadLetter, err := openDeadLetter(config)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if deadLetter != nil {
+		defer func() {
+			err := deadLetter.Close()
+			if err != nil {
+				log.Println("failed to close dead letter file", "err", err)
+			}
+		}()
+	}
+
+	// the format is validated by the configuration
+	parse, _ := commonlog.ParserFor(config.LogFormat)
+
+	var envelope *envelopeDecoder
+	if config.LogEnvelope != NoEnvelope {
+		envelope = newEnvelopeDecoder(config.LogEnvelope)
+	}
+
+	// the lines are parsed by the workers of the pool, the monitor handles them on the main goroutine.
+	// The queue is set before the api serves the statistics reading it.
+	pool := newParsePool(config.Parsing, parse, envelope)
+	monitor.Ingestion = pool
+
+	var ingest *IngestSource
+	if len(config.Ingest.Tokens) > 0 {
+		ingest = NewIngestSource(config.Ingest, parse)
+		ingest.Reject = func(line LogLine, reason string, err error) {
+			rejectLine(monitor, deadLetter, line, reason, err)
+		}
+	}
+
+	if len(config.APIAddress) > 0 {
+		api := API{Tokens: config.APITokens, Silencer: silencer, Journal: journal, Monitor: monitor, TopSectionsCount: config.StatsTopSectionsCount}
+		if ingest != nil {
+			api.Ingest = ingest
+		}
+		server := &http.Server{Addr: config.APIAddress, Handler: api.Handler()}
+		go func() {
+			log.Println("api listening", "address", config.APIAddress)
+			err := server.ListenAndServe()
+			if err != nil && err != http.ErrServerClosed {
+				log.Fatal(fmt.Sprintf("cannot serve api - err: %s", err))
+			}
+		}()
+		defer func() {
+			err := server.Close()
+			if err != nil {
+				log.Println("failed to close api", "err", err)
+			}
+		}()
 	}
 
 	log.Println("start monitoring")
-	t, err := tail.TailFile(config.LogToMonitor, tail.Config{Follow: true, ReOpen: true, Poll: true})
+	monitor.LineReceived(time.Now())
+	var checkpoints *Checkpoints
+	var checkpointTicks <-chan time.Time
+	if len(config.CheckpointFile) > 0 {
+		checkpoints, err = LoadCheckpoints(config.CheckpointFile)
+		if err != nil {
+			log.Fatal(fmt.Sprintf("cannot load checkpoints - err: %s", err))
+		}
+		// saved once the source is stopped so the positions are the ones of the handled lines
+		defer func() {
+			err := checkpoints.Save()
+			if err != nil {
+				log.Println("failed to save checkpoints", "err", err)
+			}
+		}()
+
+		checkpointTicker := time.NewTicker(config.CheckpointInterval)
+		defer checkpointTicker.Stop()
+		checkpointTicks = checkpointTicker.C
+	}
+
+	var sources []LineSource
+	switch {
+	case len(config.LogsToMonitor) == 1 && config.LogsToMonitor[0] == StdinPath:
+		sources = append(sources, NewStdinSource())
+	case len(config.LogsToMonitor) > 0:
+		sources = append(sources, NewFileSource(config.LogsToMonitor, config.DiscoveryInterval, checkpoints))
+	}
+	if len(config.SyslogAddresses) > 0 {
+		sources = append(sources, NewSyslogSource(config.SyslogAddresses))
+	}
+	if ingest != nil {
+		sources = append(sources, ingest)
+	}
+
+	var source LineSource = NewMultiSource(sources...)
+	if len(sources) == 1 {
+		source = sources[0]
+	}
+	err = source.Start()
 	if err != nil {
 		log.Fatal(err)
 	}
+	defer source.Stop()
+
+	pool.Start(source.Lines())
+	defer pool.Stop()
 
 	ctx, cancel := context.WithCancel(context.Background())
 
@@ -64,24 +227,39 @@ func main() {
 		case <-ctx.Done():
 			log.Println("stopped")
 			return
-		case line := <-t.Lines:
+		case result, ok := <-pool.Results():
+			if !ok {
+				// the end of the standard input
+				log.Println("end of the logs")
+				displayStatistics(monitor.Statistics(config.StatsTopSectionsCount))
+				return
+			}
 			if ctx.Err() != nil {
 				log.Println("context canceled")
 				return
 			}
 			// consumes the logs
+			monitor.LineReceived(time.Now())
+			line := result.line
 			if line.Err != nil {
-				log.Println("cannot read line", "err", line.Err, "line", line.Text)
+				log.Println("cannot read line", "err", line.Err, "source", line.Source, "line", line.Text)
+				rejectLine(monitor, deadLetter, line, UnreadableReason, line.Err)
 				continue
 			}
 
-			event, err := commonlog.Parse(line.Text)
-			if err != nil {
-				log.Println("cannot parse line", "err", err, "line", line.Text)
-				continue
+			switch {
+			case errors.Is(result.err, commonlog.ErrHeader):
+			case result.err != nil:
+				log.Println("cannot parse line", "err", result.err, "source", line.Source, "line", line.Text)
+				rejectLine(monitor, deadLetter, line, commonlog.Reason(result.err), result.err)
+			default:
+				monitor.HandleSampledEvent(result.event, result.weight)
 			}
 
-			monitor.HandleEvent(event)
+			// the streams have no position to resume
+			if checkpoints != nil && line.Position.Offset > 0 {
+				checkpoints.Update(line.Source, line.Position)
+			}
 		case <-c:
 			log.Println("stopping")
 			statsTicker.Stop()
@@ -90,50 +268,296 @@ func main() {
 
 		case <-statsTicker.C:
 			// display statistics
-			statistics := monitor.Statistics(config.StatsTopSectionsCount)
+			displayStatistics(monitor.Statistics(config.StatsTopSectionsCount))
 
-			log.Println("number of events received", statistics.HitsByStatus[Total])
-			for status, hits := range statistics.HitsByStatus {
-				if status == Total {
-					continue
-				}
+		case <-alertingTicker.C:
+			// check if traffic generated an alert to display
+			checkAlerts(monitor, config, reporter)
 
-				log.Println(fmt.Sprintf("hits by status %s", status), hits)
+		case <-checkpointTicks:
+			err := checkpoints.Save()
+			if err != nil {
+				log.Println("cannot save checkpoints", "err", err)
 			}
 
-			log.Println("top sections visited", len(statistics.TopSections))
-			for _, s := range statistics.TopSections {
-				log.Println("section", s.Name, "hits", s.Hits)
-			}
+		case <-cleaningTimeSeriesTicker.C:
+			// clean the older time series to avoid important memory usage
+			cleanTimeSeries(monitor, config)
+		}
+	}
+}
 
-			log.Println("total bytes", formatSize(statistics.TotalBytes))
+// UnreadableReason is the reason of the lines rejected because they cannot be read from their source
+const UnreadableReason = "unreadable"
 
-		case <-alertingTicker.C:
-			// check if traffic generated an alert to display
-			hits := monitor.HitsSeries.CountSince(time.Now().Add(-1 * config.TrafficLoadPeriod))
+// openDeadLetter opens the file of the rejected lines configured, nil when there is none
+func openDeadLetter(config Configuration) (*DeadLetter, error) {
+	if len(config.DeadLetter.Path) == 0 {
+		return nil, nil
+	}
 
-			alert := monitor.CheckTrafficLoad(hits, config.TrafficLoadPeriod, config.TrafficThreshold)
-			if alert == nil {
-				// no alerting
-				log.Println(fmt.Sprintf("traffic is normal - %v", hits))
-				continue
-			}
+	return OpenDeadLetter(config.DeadLetter)
+}
 
-			if alert.Exceed {
-				log.Println(fmt.Sprintf("high traffic generated an alert - hits: %v - rate: %v hits/s - triggered at: %s",
-					hits, alert.AverageRate, alert.TriggeredAt))
-				continue
-			}
+// rejectLine counts the line rejected by its reason and records it in the dead letter file when there is one
+func rejectLine(monitor *LogMonitor, deadLetter *DeadLetter, line LogLine, reason string, err error) {
+	monitor.LineRejected(reason)
+	if deadLetter == nil {
+		return
+	}
 
-			log.Println(fmt.Sprintf("traffic came back to normal - hits: %v - rate: %v hits/s", hits, alert.AverageRate))
+	rejected := RejectedLine{RejectedAt: time.Now(), Source: line.Source, Reason: reason, Error: err.Error(), Line: line.Text}
+	err = deadLetter.Record(rejected)
+	if err != nil {
+		log.Println("cannot record rejected line", "err", err)
+	}
+}
 
-		case <-cleaningTimeSeriesTicker.C:
-			// clean the older time series to avoid important memory usage
-			log.Println("cleaning time series")
-			cleaned := monitor.HitsSeries.Clean(time.Now().Add(-1 * config.TrafficLoadPeriod))
-			log.Println("time series cleaned", cleaned)
+// newMonitor creates the monitor of the logs configured, its alerts and statistics follow the clock
+func newMonitor(config Configuration, clock metric.Clock) *LogMonitor {
+	monitor := NewLogMonitorWithClock(clock)
+	monitor.PendingDuration = config.AlertPendingDuration
+	monitor.SLOWindow = config.SLOWindow
+	monitor.Apdex = NewApdex(config.ApdexTargets, clock)
+	monitor.StatsInterval = config.StatsDisplayInterval
+	monitor.TrackHosts = len(config.HostTrafficThresholds) > 0
+	for section, objective := range config.SLOObjectives {
+		monitor.AddSLO(section, objective)
+	}
+
+	return monitor
+}
+
+// displayStatistics displays the statistics in the logs
+func displayStatistics(statistics Statistics) {
+	log.Println("number of events received", statistics.HitsByStatus[Total])
+	for status, hits := range statistics.HitsByStatus {
+		if status == Total {
+			continue
+		}
+
+		log.Println(fmt.Sprintf("hits by status %s", status), hits)
+	}
+
+	log.Println("top sections visited", len(statistics.TopSections))
+	for _, s := range statistics.TopSections {
+		log.Println("section", s.Name, "hits", s.Hits)
+	}
+
+	log.Println("total bytes", formatSize(statistics.TotalBytes))
+
+	if len(statistics.HitsBySource) > 1 {
+		for source, hits := range statistics.HitsBySource {
+			log.Println("hits by source", source, hits)
+		}
+	}
+
+	if ingestion := statistics.Ingestion; ingestion != nil {
+		log.Println(fmt.Sprintf("ingestion queue %v/%v - dropped lines: %v - sampled lines: %v",
+			ingestion.QueueDepth, ingestion.QueueCapacity, ingestion.DroppedLines, ingestion.SampledLines))
+	}
+
+	for backend, hits := range statistics.HitsByBackend {
+		log.Println("hits by backend", backend, hits)
+	}
+
+	for reason, lines := range statistics.RejectedByReason {
+		log.Println("lines rejected by reason", reason, lines)
+	}
+
+	for _, a := range statistics.Apdex {
+		log.Println(fmt.Sprintf("apdex section /%s - score: %.2f - target: %s - satisfied: %v - tolerating: %v - hits: %v",
+			a.Section, a.Score, a.Target, a.Satisfied, a.Tolerating, a.Total))
+	}
+
+	for _, b := range statistics.ErrorBudgets {
+		log.Println(fmt.Sprintf("slo section /%s - objective: %.3f%% - availability: %.3f%% - remaining error budget: %.1f%% - hits: %v",
+			b.Section, b.Objective*100, b.Availability*100, b.Remaining*100, b.Total))
+	}
+}
+
+// checkAlerts evaluates the alerting rules configured and reports their alerts
+func checkAlerts(monitor *LogMonitor, config Configuration, reporter *alertReporter) {
+	since := monitor.Clock.Now().Add(-1 * config.TrafficLoadPeriod)
+	hits := monitor.HitsSeries.CountSince(since)
+
+	alert := monitor.CheckTrafficLoad(hits, config.TrafficLoadPeriod, config.TrafficThreshold)
+	if alert == nil {
+		// no alerting
+		log.Println(fmt.Sprintf("traffic is normal - %v", hits))
+	} else {
+		reporter.report(alert)
+	}
+
+	if config.TrafficLowThreshold > 0 {
+		alert = monitor.CheckTrafficDrop(hits, config.TrafficLoadPeriod, config.TrafficLowThreshold)
+		if alert != nil {
+			reporter.report(alert)
+		}
+	}
+
+	if len(config.SectionTrafficThresholds) > 0 {
+		hits := monitor.SectionHitsSeries.AllCountsSince(since)
+		for _, alert := range monitor.CheckScopedTrafficLoad(SectionScope, hits, config.TrafficLoadPeriod, config.SectionTrafficThresholds) {
+			reporter.report(alert)
+		}
+	}
+
+	if len(config.HostTrafficThresholds) > 0 {
+		hits := monitor.HostHitsSeries.AllCountsSince(since)
+		for _, alert := range monitor.CheckScopedTrafficLoad(HostScope, hits, config.TrafficLoadPeriod, config.HostTrafficThresholds) {
+			reporter.report(alert)
 		}
 	}
+
+	if len(config.TerminationStateThresholds) > 0 {
+		hits := monitor.TerminationHitsSeries.AllCountsSince(since)
+		for _, alert := range monitor.CheckTerminationStates(hits, config.TrafficLoadPeriod, config.TerminationStateThresholds) {
+			reporter.report(alert)
+		}
+	}
+
+	if len(config.ApdexThresholds) > 0 {
+		for _, alert := range monitor.CheckApdex(config.TrafficLoadPeriod, config.ApdexThresholds) {
+			reporter.report(alert)
+		}
+	}
+
+	for _, alert := range monitor.CheckBurnRates() {
+		reporter.report(alert)
+	}
+
+	if config.NoDataTimeout > 0 {
+		alert = monitor.CheckNoData(config.NoDataTimeout)
+		if alert != nil {
+			reporter.report(alert)
+		}
+	}
+}
+
+// cleanTimeSeries cleans the older time series to avoid important memory usage
+func cleanTimeSeries(monitor *LogMonitor, config Configuration) {
+	log.Println("cleaning time series")
+	now := monitor.Clock.Now()
+	cleaned := monitor.HitsSeries.Clean(now.Add(-1 * config.TrafficLoadPeriod))
+	cleaned += monitor.SectionHitsSeries.Clean(now.Add(-1 * config.TrafficLoadPeriod))
+	cleaned += monitor.HostHitsSeries.Clean(now.Add(-1 * config.TrafficLoadPeriod))
+	cleaned += monitor.TerminationHitsSeries.Clean(now.Add(-1 * config.TrafficLoadPeriod))
+	apdexRetention := config.TrafficLoadPeriod
+	if config.StatsDisplayInterval > apdexRetention {
+		apdexRetention = config.StatsDisplayInterval
+	}
+	cleaned += monitor.Apdex.Clean(now.Add(-1 * apdexRetention))
+	for _, slo := range monitor.SLOs {
+		cleaned += slo.Clean(now.Add(-1 * config.SLOWindow))
+	}
+	log.Println("time series cleaned", cleaned)
+}
+
+// alertReporter displays the alerts, records their transitions in the journal and notifies them
+type alertReporter struct {
+	notifier AlertNotifier
+	journal  *Journal // optional
+
+	// interval to notify again the firing alerts, 0 to notify them once
+	repeatInterval time.Duration
+
+	states   map[string]AlertState // last state recorded by fingerprint
+	notified map[string]time.Time  // last notification of the firing alerts by fingerprint
+}
+
+func newAlertReporter(notifier AlertNotifier, journal *Journal, repeatInterval time.Duration) *alertReporter {
+	return &alertReporter{
+		notifier:       notifier,
+		journal:        journal,
+		repeatInterval: repeatInterval,
+		states:         make(map[string]AlertState),
+		notified:       make(map[string]time.Time),
+	}
+}
+
+// report displays the alert, records its state when it changed and notifies its transitions.
+// A firing alert is notified again after the repeat interval, pending alerts are not notified.
+func (r *alertReporter) report(alert *Alert) {
+	displayAlert(alert)
+
+	fingerprint := alert.Fingerprint()
+	if r.states[fingerprint] != alert.State && r.journal != nil {
+		err := r.journal.Record(*alert)
+		if err != nil {
+			log.Println("cannot record alert in the journal", "err", err)
+		}
+	}
+	r.states[fingerprint] = alert.State
+	if alert.State == AlertResolved {
+		delete(r.states, fingerprint)
+	}
+
+	switch alert.State {
+	case AlertPending:
+		return
+	case AlertFiring:
+		last, ok := r.notified[fingerprint]
+		if ok && (r.repeatInterval == 0 || alert.TriggeredAt.Sub(last) < r.repeatInterval) {
+			return
+		}
+		r.notified[fingerprint] = alert.TriggeredAt
+	case AlertResolved:
+		if _, ok := r.notified[fingerprint]; !ok {
+			// the alert never fired
+			return
+		}
+		delete(r.notified, fingerprint)
+	}
+
+	err := r.notifier.Notify(*alert)
+	if err != nil {
+		log.Println("cannot notify alert", "err", err)
+	}
+}
+
+// displays the alert in the logs
+func displayAlert(alert *Alert) {
+	firing := alert.State == AlertFiring
+	switch {
+	case alert.State == AlertPending:
+		log.Println(fmt.Sprintf("%s alert pending - %s - active since: %s", alert.Name, alert.Description(), alert.ActiveAt))
+	case (alert.Name == SLOFastBurnAlert || alert.Name == SLOSlowBurnAlert) && firing:
+		log.Println(fmt.Sprintf("%s is burning its error budget - %s - triggered at: %s", alert.Subject(), alert.Description(), alert.TriggeredAt))
+	case alert.Name == SLOFastBurnAlert || alert.Name == SLOSlowBurnAlert:
+		log.Println(fmt.Sprintf("%s error budget burn rate came back to normal - burn rate: %.2f", alert.Subject(), alert.Value))
+	case alert.Name == LowApdexAlert && firing:
+		log.Println(fmt.Sprintf("%s apdex fell below its threshold - %s - triggered at: %s", alert.Subject(), alert.Description(), alert.TriggeredAt))
+	case alert.Name == LowApdexAlert:
+		log.Println(fmt.Sprintf("%s apdex came back to normal - apdex: %.2f", alert.Subject(), alert.Value))
+	case alert.Name == TerminationStateAlert && firing:
+		log.Println(fmt.Sprintf("too many proxy sessions ended with %s - %s - triggered at: %s", alert.Subject(), alert.Description(), alert.TriggeredAt))
+	case alert.Name == TerminationStateAlert:
+		log.Println(fmt.Sprintf("%s came back to normal - sessions: %v", alert.Subject(), alert.Hits))
+	case alert.Scope == SectionScope && firing:
+		log.Println(fmt.Sprintf("%s exceeded %v req/s - hits: %v - rate: %v hits/s - triggered at: %s",
+			alert.Subject(), alert.Threshold, alert.Hits, alert.AverageRate, alert.TriggeredAt))
+	case alert.Scope == HostScope && firing:
+		log.Println(fmt.Sprintf("%s is sending %v req/s - threshold: %v req/s - hits: %v - triggered at: %s",
+			alert.Subject(), alert.AverageRate, alert.Threshold, alert.Hits, alert.TriggeredAt))
+	case alert.Scope != "":
+		log.Println(fmt.Sprintf("%s came back to normal - hits: %v - rate: %v hits/s", alert.Subject(), alert.Hits, alert.AverageRate))
+	case alert.Name == HighTrafficAlert && firing:
+		log.Println(fmt.Sprintf("high traffic generated an alert - hits: %v - rate: %v hits/s - triggered at: %s",
+			alert.Hits, alert.AverageRate, alert.TriggeredAt))
+	case alert.Name == HighTrafficAlert:
+		log.Println(fmt.Sprintf("traffic came back to normal - hits: %v - rate: %v hits/s", alert.Hits, alert.AverageRate))
+	case alert.Name == LowTrafficAlert && firing:
+		log.Println(fmt.Sprintf("low traffic generated an alert - hits: %v - rate: %v hits/s - threshold: %v hits/s - triggered at: %s",
+			alert.Hits, alert.AverageRate, alert.Threshold, alert.TriggeredAt))
+	case alert.Name == LowTrafficAlert:
+		log.Println(fmt.Sprintf("traffic came back above the low threshold - hits: %v - rate: %v hits/s", alert.Hits, alert.AverageRate))
+	case alert.Name == NoDataAlert && firing:
+		log.Println(fmt.Sprintf("no data generated an alert - no line received for at least %vs - triggered at: %s",
+			alert.Threshold, alert.TriggeredAt))
+	case alert.Name == NoDataAlert:
+		log.Println("log lines are received again")
+	}
 }
 
 // format a size in a human readable string
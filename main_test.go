@@ -7,7 +7,6 @@ import (
 	"time"
 
 	"github.com/ali.ghanem/http-log-monitoring/commonlog"
-	"github.com/ali.ghanem/http-log-monitoring/metric"
 	"github.com/ali.ghanem/http-log-monitoring/timetest"
 )
 
@@ -75,6 +74,74 @@ func TestLogMonitor_HandleEvent(t *testing.T) {
 	}
 }
 
+// queueMock is an ingestion queue with fixed values
+type queueMock Ingestion
+
+func (q queueMock) Depth() int     { return q.QueueDepth }
+func (q queueMock) Capacity() int  { return q.QueueCapacity }
+func (q queueMock) Dropped() int64 { return q.DroppedLines }
+func (q queueMock) Sampled() int64 { return q.SampledLines }
+
+func TestLogMonitor_HandleSampledEvent(t *testing.T) {
+	m := setupLogMonitor(t)
+	m.Ingestion = queueMock{QueueDepth: 2000, QueueCapacity: 2000, SampledLines: 9}
+
+	date := time.Now().Truncate(time.Second).Add(-10 * time.Second)
+	m.HandleSampledEvent(commonlog.Event{Host: "10.1.2.3", Date: date, Status: http.StatusOK, Bytes: 100, Section: "api"}, 10)
+
+	statistics := m.Statistics(1)
+	expectedCalls := map[string]int64{"total": 20, "succeed": 10}
+	if !reflect.DeepEqual(expectedCalls, statistics.HitsByStatus) {
+		t.Fatal("unexpected calls", "expected", expectedCalls, "actual", statistics.HitsByStatus)
+	}
+	if statistics.TotalBytes != 11000 {
+		t.Fatal("unexpected size", "expected", 11000, "actual", statistics.TotalBytes)
+	}
+	if hits := m.SectionHitsSeries.CountSince("api", date); hits != 10 {
+		t.Fatal("unexpected section hits", "expected", 10, "actual", hits)
+	}
+
+	expectedIngestion := &Ingestion{QueueDepth: 2000, QueueCapacity: 2000, SampledLines: 9}
+	if !reflect.DeepEqual(expectedIngestion, statistics.Ingestion) {
+		t.Fatal("unexpected ingestion", "expected", expectedIngestion, "actual", statistics.Ingestion)
+	}
+}
+
+func TestLogMonitor_HandleEvent_TrackHosts(t *testing.T) {
+	date := time.Now().Truncate(time.Second).Add(-10 * time.Second)
+	event := commonlog.Event{Host: "10.1.2.3", Date: date, Status: http.StatusOK, Section: "api"}
+
+	for _, trackHosts := range []bool{false, true} {
+		m := setupLogMonitor(t)
+		m.TrackHosts = trackHosts
+		m.HandleEvent(event)
+
+		expected := map[string]int64{}
+		if trackHosts {
+			expected["10.1.2.3"] = 1
+		}
+		if actual := m.HostHitsSeries.AllCountsSince(date); !reflect.DeepEqual(expected, actual) {
+			t.Fatal("unexpected host hits", "track hosts", trackHosts, "expected", expected, "actual", actual)
+		}
+	}
+}
+
+func TestLogMonitor_LineRejected(t *testing.T) {
+	m := setupLogMonitor(t)
+	if statistics := m.Statistics(1); statistics.RejectedByReason != nil {
+		t.Fatal("unexpected rejected lines", "expected", nil, "actual", statistics.RejectedByReason)
+	}
+
+	for _, reason := range []string{"bad_date", "no_section", "bad_date", UnreadableReason} {
+		m.LineRejected(reason)
+	}
+
+	expected := map[string]int64{"bad_date": 2, "no_section": 1, UnreadableReason: 1}
+	if statistics := m.Statistics(1); !reflect.DeepEqual(expected, statistics.RejectedByReason) {
+		t.Fatal("unexpected rejected lines", "expected", expected, "actual", statistics.RejectedByReason)
+	}
+}
+
 func TestLogMonitor_Statistics(t *testing.T) {
 	type testCase struct {
 		Events             []commonlog.Event
@@ -173,6 +240,35 @@ func TestLogMonitor_Statistics(t *testing.T) {
 	}
 }
 
+func TestLogMonitor_SourceStatistics(t *testing.T) {
+	m := setupLogMonitor(t)
+
+	date := time.Now().Truncate(time.Second).Add(-10 * time.Second)
+	m.HandleEvent(commonlog.Event{Date: date, Status: http.StatusOK, Bytes: 100, Section: "api", Source: "api.log"})
+	m.HandleEvent(commonlog.Event{Date: date, Status: http.StatusNotFound, Bytes: 50, Section: "api", Source: "api.log"})
+	m.HandleEvent(commonlog.Event{Date: date, Status: http.StatusOK, Bytes: 10, Section: "shop", Source: "shop.log"})
+
+	expected := Statistics{
+		TopSections:  []Section{{Name: "api", Hits: 2}},
+		HitsByStatus: map[string]int64{"total": 2, "succeed": 1, "client_error": 1},
+		TotalBytes:   150,
+	}
+	actual, ok := m.SourceStatistics("api.log", 3)
+	if !ok || !reflect.DeepEqual(expected, actual) {
+		t.Fatal("unexpected statistics", "expected", expected, "actual", actual)
+	}
+
+	_, ok = m.SourceStatistics("unknown.log", 3)
+	if ok {
+		t.Fatal("unexpected statistics of an unknown source")
+	}
+
+	expectedHits := map[string]int64{"api.log": 2, "shop.log": 1}
+	if hits := m.Statistics(3).HitsBySource; !reflect.DeepEqual(expectedHits, hits) {
+		t.Fatal("unexpected hits by source", "expected", expectedHits, "actual", hits)
+	}
+}
+
 func TestLogMonitor_CheckTrafficLoad(t *testing.T) {
 	type testCase struct {
 		LastAlert         *Alert
@@ -200,55 +296,83 @@ func TestLogMonitor_CheckTrafficLoad(t *testing.T) {
 			Interval:  time.Minute,
 			Threshold: 100,
 			ExpectedAlert: &Alert{
-				Exceed:      true,
+				Name:        HighTrafficAlert,
+				State:       AlertFiring,
 				Hits:        10000,
 				AverageRate: 166,
+				Threshold:   100,
+				ActiveAt:    triggeredAt,
+				StartsAt:    triggeredAt,
 				TriggeredAt: triggeredAt,
 			},
 			ExpectedLastAlert: &Alert{
-				Exceed:      true,
+				Name:        HighTrafficAlert,
+				State:       AlertFiring,
 				Hits:        10000,
 				AverageRate: 166,
+				Threshold:   100,
+				ActiveAt:    triggeredAt,
+				StartsAt:    triggeredAt,
 				TriggeredAt: triggeredAt,
 			},
 		},
-		"new alert because traffic load increased": {
+		"ongoing alert updated because traffic load increased": {
 			LastAlert: &Alert{
-				Exceed:      true,
+				Name:        HighTrafficAlert,
+				State:       AlertFiring,
 				Hits:        10000,
 				AverageRate: 166,
+				Threshold:   100,
+				ActiveAt:    triggeredAt,
+				StartsAt:    triggeredAt,
 				TriggeredAt: triggeredAt,
 			},
 			Hits:      20000,
 			Interval:  time.Minute,
 			Threshold: 100,
 			ExpectedAlert: &Alert{
-				Exceed:      true,
+				Name:        HighTrafficAlert,
+				State:       AlertFiring,
 				Hits:        20000,
 				AverageRate: 333,
+				Threshold:   100,
+				ActiveAt:    triggeredAt,
+				StartsAt:    triggeredAt,
 				TriggeredAt: triggeredAt,
 			},
 			ExpectedLastAlert: &Alert{
-				Exceed:      true,
+				Name:        HighTrafficAlert,
+				State:       AlertFiring,
 				Hits:        20000,
 				AverageRate: 333,
+				Threshold:   100,
+				ActiveAt:    triggeredAt,
+				StartsAt:    triggeredAt,
 				TriggeredAt: triggeredAt,
 			},
 		},
 		"traffic load is back to normal": {
 			LastAlert: &Alert{
-				Exceed:      true,
+				Name:        HighTrafficAlert,
+				State:       AlertFiring,
 				Hits:        20000,
 				AverageRate: 333,
+				Threshold:   100,
+				ActiveAt:    triggeredAt,
+				StartsAt:    triggeredAt,
 				TriggeredAt: triggeredAt,
 			},
 			Hits:      1000,
 			Interval:  time.Minute,
 			Threshold: 100,
 			ExpectedAlert: &Alert{
-				Exceed:      false,
+				Name:        HighTrafficAlert,
+				State:       AlertResolved,
 				Hits:        1000,
 				AverageRate: 16,
+				Threshold:   100,
+				ActiveAt:    triggeredAt,
+				StartsAt:    triggeredAt,
 				TriggeredAt: triggeredAt,
 			},
 			ExpectedLastAlert: nil,
@@ -275,18 +399,598 @@ func TestLogMonitor_CheckTrafficLoad(t *testing.T) {
 	}
 }
 
-func setupLogMonitor(t *testing.T) *LogMonitor {
-	m := LogMonitor{
-		Sections:   metric.NewCounterVec(),
-		HitsSeries: metric.NewTimeSeries(),
-		Calls:      metric.NewCounterVec(),
-		Bytes:      metric.NewCounter(),
+func TestLogMonitor_CheckTrafficDrop(t *testing.T) {
+	type testCase struct {
+		LastAlert         *Alert
+		Hits              int64
+		Interval          time.Duration
+		Threshold         int64
+		FirstCheck        bool // the traffic has not been checked before
+		ExpectedAlert     *Alert
+		ExpectedLastAlert *Alert
 	}
 
+	triggeredAt := time.Date(2006, 01, 02, 15, 04, 05, 000, time.UTC)
+
+	cases := map[string]testCase{
+		"traffic not evaluated at the first check": {
+			LastAlert:         nil,
+			Hits:              0,
+			Interval:          time.Minute,
+			Threshold:         10,
+			FirstCheck:        true,
+			ExpectedAlert:     nil,
+			ExpectedLastAlert: nil,
+		},
+		"no alert occurred traffic is normal": {
+			LastAlert:         nil,
+			Hits:              1200,
+			Interval:          time.Minute,
+			Threshold:         10,
+			ExpectedAlert:     nil,
+			ExpectedLastAlert: nil,
+		},
+		"alert because traffic is below the threshold": {
+			LastAlert: nil,
+			Hits:      300,
+			Interval:  time.Minute,
+			Threshold: 10,
+			ExpectedAlert: &Alert{
+				Name:        LowTrafficAlert,
+				State:       AlertFiring,
+				Hits:        300,
+				AverageRate: 5,
+				Threshold:   10,
+				ActiveAt:    triggeredAt,
+				StartsAt:    triggeredAt,
+				TriggeredAt: triggeredAt,
+			},
+			ExpectedLastAlert: &Alert{
+				Name:        LowTrafficAlert,
+				State:       AlertFiring,
+				Hits:        300,
+				AverageRate: 5,
+				Threshold:   10,
+				ActiveAt:    triggeredAt,
+				StartsAt:    triggeredAt,
+				TriggeredAt: triggeredAt,
+			},
+		},
+		"alert because the site went dark": {
+			LastAlert: nil,
+			Hits:      0,
+			Interval:  time.Minute,
+			Threshold: 1,
+			ExpectedAlert: &Alert{
+				Name:        LowTrafficAlert,
+				State:       AlertFiring,
+				Hits:        0,
+				AverageRate: 0,
+				Threshold:   1,
+				ActiveAt:    triggeredAt,
+				StartsAt:    triggeredAt,
+				TriggeredAt: triggeredAt,
+			},
+			ExpectedLastAlert: &Alert{
+				Name:        LowTrafficAlert,
+				State:       AlertFiring,
+				Hits:        0,
+				AverageRate: 0,
+				Threshold:   1,
+				ActiveAt:    triggeredAt,
+				StartsAt:    triggeredAt,
+				TriggeredAt: triggeredAt,
+			},
+		},
+		"traffic load is back above the threshold": {
+			LastAlert: &Alert{
+				Name:        LowTrafficAlert,
+				State:       AlertFiring,
+				Hits:        300,
+				AverageRate: 5,
+				Threshold:   10,
+				ActiveAt:    triggeredAt,
+				StartsAt:    triggeredAt,
+				TriggeredAt: triggeredAt,
+			},
+			Hits:      1200,
+			Interval:  time.Minute,
+			Threshold: 10,
+			ExpectedAlert: &Alert{
+				Name:        LowTrafficAlert,
+				State:       AlertResolved,
+				Hits:        1200,
+				AverageRate: 20,
+				Threshold:   10,
+				ActiveAt:    triggeredAt,
+				StartsAt:    triggeredAt,
+				TriggeredAt: triggeredAt,
+			},
+			ExpectedLastAlert: nil,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			timetest.FreezeTime()
+			defer timetest.UnfreezeTime()
+
+			m := setupLogMonitor(t)
+			m.LastLowTrafficAlert = c.LastAlert
+			if !c.FirstCheck {
+				m.LowTrafficCheckedSince = time.Now().Add(-c.Interval)
+			}
+
+			alert := m.CheckTrafficDrop(c.Hits, c.Interval, c.Threshold)
+			if !reflect.DeepEqual(c.ExpectedAlert, alert) {
+				t.Fatal("unexpected alert", "expected", c.ExpectedAlert, "actual", alert)
+			}
+
+			if !reflect.DeepEqual(c.ExpectedLastAlert, m.LastLowTrafficAlert) {
+				t.Fatal("unexpected alert saved", "expected", c.ExpectedLastAlert, "actual", m.LastLowTrafficAlert)
+			}
+		})
+	}
+}
+
+func TestLogMonitor_CheckNoData(t *testing.T) {
+	type testCase struct {
+		LastAlert         *Alert
+		LastLineReceived  time.Duration // elapsed time since the last line
+		Timeout           time.Duration
+		ExpectedAlert     *Alert
+		ExpectedLastAlert *Alert
+	}
+
+	triggeredAt := time.Date(2006, 01, 02, 15, 04, 05, 000, time.UTC)
+	noDataAlert := &Alert{
+		Name:        NoDataAlert,
+		State:       AlertFiring,
+		Threshold:   30,
+		ActiveAt:    triggeredAt,
+		StartsAt:    triggeredAt,
+		TriggeredAt: triggeredAt,
+	}
+
+	cases := map[string]testCase{
+		"lines are flowing": {
+			LastAlert:         nil,
+			LastLineReceived:  5 * time.Second,
+			Timeout:           30 * time.Second,
+			ExpectedAlert:     nil,
+			ExpectedLastAlert: nil,
+		},
+		"alert because no line received": {
+			LastAlert:         nil,
+			LastLineReceived:  45 * time.Second,
+			Timeout:           30 * time.Second,
+			ExpectedAlert:     noDataAlert,
+			ExpectedLastAlert: noDataAlert,
+		},
+		"still no line received returns the original alert": {
+			LastAlert:         noDataAlert,
+			LastLineReceived:  2 * time.Minute,
+			Timeout:           30 * time.Second,
+			ExpectedAlert:     noDataAlert,
+			ExpectedLastAlert: noDataAlert,
+		},
+		"lines are received again": {
+			LastAlert:        noDataAlert,
+			LastLineReceived: time.Second,
+			Timeout:          30 * time.Second,
+			ExpectedAlert: &Alert{
+				Name:        NoDataAlert,
+				State:       AlertResolved,
+				Threshold:   30,
+				ActiveAt:    triggeredAt,
+				StartsAt:    triggeredAt,
+				TriggeredAt: triggeredAt,
+			},
+			ExpectedLastAlert: nil,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			timetest.FreezeTime()
+			defer timetest.UnfreezeTime()
+
+			m := setupLogMonitor(t)
+			m.LastNoDataAlert = c.LastAlert
+			m.LineReceived(triggeredAt.Add(-1 * c.LastLineReceived))
+
+			alert := m.CheckNoData(c.Timeout)
+			if !reflect.DeepEqual(c.ExpectedAlert, alert) {
+				t.Fatal("unexpected alert", "expected", c.ExpectedAlert, "actual", alert)
+			}
+
+			if !reflect.DeepEqual(c.ExpectedLastAlert, m.LastNoDataAlert) {
+				t.Fatal("unexpected alert saved", "expected", c.ExpectedLastAlert, "actual", m.LastNoDataAlert)
+			}
+		})
+	}
+}
+
+func setupLogMonitor(t *testing.T) *LogMonitor {
+	m := NewLogMonitor()
+
 	m.Bytes.Inc(10000)
 	m.Sections.Inc("pages", 10)
 	m.Calls.Inc(Total, 10)
 	m.HitsSeries.Inc(time.Now().Truncate(time.Second).Add(-10*time.Second), 10)
 
-	return &m
+	return m
+}
+
+func TestLogMonitor_CheckScopedTrafficLoad(t *testing.T) {
+	type testCase struct {
+		LastAlerts         map[string]*Alert
+		Scope              string
+		Hits               map[string]int64
+		Thresholds         map[string]int64
+		ExpectedAlerts     []*Alert
+		ExpectedLastAlerts map[string]*Alert
+	}
+
+	triggeredAt := time.Date(2006, 01, 02, 15, 04, 05, 000, time.UTC)
+	loginAlert := &Alert{
+		Name:        HighTrafficAlert,
+		Scope:       SectionScope,
+		Key:         "login",
+		State:       AlertFiring,
+		Hits:        6000,
+		AverageRate: 100,
+		Threshold:   50,
+		ActiveAt:    triggeredAt,
+		StartsAt:    triggeredAt,
+		TriggeredAt: triggeredAt,
+	}
+
+	cases := map[string]testCase{
+		"sections under their threshold": {
+			LastAlerts:         map[string]*Alert{},
+			Scope:              SectionScope,
+			Hits:               map[string]int64{"login": 600, "checkout": 6000},
+			Thresholds:         map[string]int64{"login": 50},
+			ExpectedAlerts:     nil,
+			ExpectedLastAlerts: map[string]*Alert{},
+		},
+		"section exceeds its threshold": {
+			LastAlerts:         map[string]*Alert{},
+			Scope:              SectionScope,
+			Hits:               map[string]int64{"login": 6000, "checkout": 6000},
+			Thresholds:         map[string]int64{"login": 50},
+			ExpectedAlerts:     []*Alert{loginAlert},
+			ExpectedLastAlerts: map[string]*Alert{"section|login": loginAlert},
+		},
+		"section without hits is back to normal": {
+			LastAlerts: map[string]*Alert{"section|login": loginAlert},
+			Scope:      SectionScope,
+			Hits:       map[string]int64{"checkout": 6000},
+			Thresholds: map[string]int64{"login": 50},
+			ExpectedAlerts: []*Alert{
+				{
+					Name:        HighTrafficAlert,
+					Scope:       SectionScope,
+					Key:         "login",
+					State:       AlertResolved,
+					Threshold:   50,
+					ActiveAt:    triggeredAt,
+					StartsAt:    triggeredAt,
+					TriggeredAt: triggeredAt,
+				},
+			},
+			ExpectedLastAlerts: map[string]*Alert{},
+		},
+		"any host exceeds the default threshold": {
+			LastAlerts: map[string]*Alert{"section|login": loginAlert},
+			Scope:      HostScope,
+			Hits:       map[string]int64{"10.1.2.3": 18000, "10.1.2.4": 60, "10.1.2.5": 6000},
+			Thresholds: map[string]int64{AnyKey: 200, "10.1.2.5": 500},
+			ExpectedAlerts: []*Alert{
+				{
+					Name:        HighTrafficAlert,
+					Scope:       HostScope,
+					Key:         "10.1.2.3",
+					State:       AlertFiring,
+					Hits:        18000,
+					AverageRate: 300,
+					Threshold:   200,
+					ActiveAt:    triggeredAt,
+					StartsAt:    triggeredAt,
+					TriggeredAt: triggeredAt,
+				},
+			},
+			ExpectedLastAlerts: map[string]*Alert{
+				"section|login": loginAlert,
+				"host|10.1.2.3": {
+					Name:        HighTrafficAlert,
+					Scope:       HostScope,
+					Key:         "10.1.2.3",
+					State:       AlertFiring,
+					Hits:        18000,
+					AverageRate: 300,
+					Threshold:   200,
+					ActiveAt:    triggeredAt,
+					StartsAt:    triggeredAt,
+					TriggeredAt: triggeredAt,
+				},
+			},
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			timetest.FreezeTime()
+			defer timetest.UnfreezeTime()
+
+			m := setupLogMonitor(t)
+			m.LastScopedAlerts = c.LastAlerts
+
+			alerts := m.CheckScopedTrafficLoad(c.Scope, c.Hits, time.Minute, c.Thresholds)
+			if !reflect.DeepEqual(c.ExpectedAlerts, alerts) {
+				t.Fatal("unexpected alerts", "expected", c.ExpectedAlerts, "actual", alerts)
+			}
+
+			if !reflect.DeepEqual(c.ExpectedLastAlerts, m.LastScopedAlerts) {
+				t.Fatal("unexpected alerts saved", "expected", c.ExpectedLastAlerts, "actual", m.LastScopedAlerts)
+			}
+		})
+	}
+}
+
+func TestLogMonitor_CheckTerminationStates(t *testing.T) {
+	type testCase struct {
+		LastAlerts         map[string]*Alert
+		Hits               map[string]int64
+		Thresholds         map[string]int64
+		ExpectedAlerts     []*Alert
+		ExpectedLastAlerts map[string]*Alert
+	}
+
+	triggeredAt := time.Date(2006, 01, 02, 15, 04, 05, 000, time.UTC)
+	timeoutAlert := &Alert{
+		Name:        TerminationStateAlert,
+		Scope:       TerminationScope,
+		Key:         "sH",
+		State:       AlertFiring,
+		Hits:        12,
+		Threshold:   10,
+		ActiveAt:    triggeredAt,
+		StartsAt:    triggeredAt,
+		TriggeredAt: triggeredAt,
+	}
+
+	cases := map[string]testCase{
+		"states under their threshold": {
+			LastAlerts:         map[string]*Alert{},
+			Hits:               map[string]int64{"sH": 3, "cD": 40},
+			Thresholds:         map[string]int64{"sH": 10, AnyKey: 50},
+			ExpectedAlerts:     nil,
+			ExpectedLastAlerts: map[string]*Alert{},
+		},
+		"server timeouts exceed their threshold": {
+			LastAlerts:         map[string]*Alert{},
+			Hits:               map[string]int64{"sH": 12, "cD": 40},
+			Thresholds:         map[string]int64{"sH": 10},
+			ExpectedAlerts:     []*Alert{timeoutAlert},
+			ExpectedLastAlerts: map[string]*Alert{"termination_state|sH": timeoutAlert},
+		},
+		"server timeouts are back to normal": {
+			LastAlerts: map[string]*Alert{"termination_state|sH": timeoutAlert},
+			Hits:       map[string]int64{"cD": 40},
+			Thresholds: map[string]int64{"sH": 10},
+			ExpectedAlerts: []*Alert{
+				{
+					Name:        TerminationStateAlert,
+					Scope:       TerminationScope,
+					Key:         "sH",
+					State:       AlertResolved,
+					Threshold:   10,
+					ActiveAt:    triggeredAt,
+					StartsAt:    triggeredAt,
+					TriggeredAt: triggeredAt,
+				},
+			},
+			ExpectedLastAlerts: map[string]*Alert{},
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			timetest.FreezeTime()
+			defer timetest.UnfreezeTime()
+
+			m := setupLogMonitor(t)
+			m.LastScopedAlerts = c.LastAlerts
+
+			alerts := m.CheckTerminationStates(c.Hits, time.Minute, c.Thresholds)
+			if !reflect.DeepEqual(c.ExpectedAlerts, alerts) {
+				t.Fatal("unexpected alerts", "expected", c.ExpectedAlerts, "actual", alerts)
+			}
+
+			if !reflect.DeepEqual(c.ExpectedLastAlerts, m.LastScopedAlerts) {
+				t.Fatal("unexpected alerts saved", "expected", c.ExpectedLastAlerts, "actual", m.LastScopedAlerts)
+			}
+		})
+	}
+}
+
+func TestLogMonitor_HandleEvent_Proxy(t *testing.T) {
+	m := setupLogMonitor(t)
+
+	date := time.Now().Truncate(time.Second).Add(-10 * time.Second)
+	states := []string{"----", "sH--", "sH--", "cD--"}
+	for i, state := range states {
+		backend := "static"
+		if i > 0 {
+			backend = "dynamic"
+		}
+		m.HandleEvent(commonlog.Event{
+			Date:    date,
+			Status:  http.StatusOK,
+			Section: "api",
+			Fields:  map[string]string{commonlog.BackendField: backend, commonlog.TerminationStateField: state},
+		})
+	}
+
+	expectedBackends := map[string]int64{"static": 1, "dynamic": 3}
+	if hits := m.Statistics(3).HitsByBackend; !reflect.DeepEqual(expectedBackends, hits) {
+		t.Fatal("unexpected hits by backend", "expected", expectedBackends, "actual", hits)
+	}
+
+	expectedStates := map[string]int64{"sH": 2, "cD": 1}
+	if hits := m.TerminationHitsSeries.AllCountsSince(date.Add(-1 * time.Minute)); !reflect.DeepEqual(expectedStates, hits) {
+		t.Fatal("unexpected hits by termination state", "expected", expectedStates, "actual", hits)
+	}
+}
+
+func TestAlert_Subject(t *testing.T) {
+	cases := map[string]Alert{
+		"traffic":              {Name: HighTrafficAlert},
+		"section /login":       {Name: HighTrafficAlert, Scope: SectionScope, Key: "login"},
+		"client 10.1.2.3":      {Name: HighTrafficAlert, Scope: HostScope, Key: "10.1.2.3"},
+		"termination state sH": {Name: TerminationStateAlert, Scope: TerminationScope, Key: "sH"},
+	}
+
+	for expected, alert := range cases {
+		t.Run(expected, func(t *testing.T) {
+			actual := alert.Subject()
+			if expected != actual {
+				t.Fatal("unexpected subject", "expected", expected, "actual", actual)
+			}
+		})
+	}
+}
+
+func TestAlert_Fingerprint(t *testing.T) {
+	alert := Alert{Name: HighTrafficAlert, Scope: SectionScope, Key: "login", State: AlertFiring, Hits: 6000}
+
+	// the values of the checks and the transitions do not change the identity of the alert
+	updated := alert
+	updated.State = AlertResolved
+	updated.Hits = 60
+	updated.TriggeredAt = time.Now()
+	if alert.Fingerprint() != updated.Fingerprint() {
+		t.Fatal("unexpected fingerprint", "expected", alert.Fingerprint(), "actual", updated.Fingerprint())
+	}
+
+	others := []Alert{
+		{Name: HighTrafficAlert},
+		{Name: LowTrafficAlert, Scope: SectionScope, Key: "login"},
+		{Name: HighTrafficAlert, Scope: SectionScope, Key: "checkout"},
+		{Name: HighTrafficAlert, Scope: HostScope, Key: "login"},
+	}
+	for _, other := range others {
+		if alert.Fingerprint() == other.Fingerprint() {
+			t.Fatal("unexpected fingerprint collision", "alert", alert.ID(), "other", other.ID())
+		}
+	}
+}
+
+func TestAlertReporter_Report(t *testing.T) {
+	notifier := &alertRecorder{}
+	reporter := newAlertReporter(notifier, nil, 0)
+
+	firing := &Alert{Name: HighTrafficAlert, State: AlertFiring, Hits: 10000}
+	resolved := &Alert{Name: HighTrafficAlert, State: AlertResolved, Hits: 100}
+
+	// the ongoing alert is reported at each check but notified once
+	reporter.report(firing)
+	reporter.report(firing)
+	reporter.report(resolved)
+
+	expected := []Alert{*firing, *resolved}
+	if !reflect.DeepEqual(expected, notifier.alerts) {
+		t.Fatal("unexpected alerts notified", "expected", expected, "actual", notifier.alerts)
+	}
+}
+
+func TestAlertReporter_RepeatInterval(t *testing.T) {
+	notifier := &alertRecorder{}
+	reporter := newAlertReporter(notifier, nil, 10*time.Minute)
+
+	startsAt := time.Date(2006, 01, 02, 15, 04, 05, 000, time.UTC)
+	alert := &Alert{Name: HighTrafficAlert, State: AlertFiring, StartsAt: startsAt}
+
+	// the ongoing alert is notified again once the repeat interval elapsed
+	var expected []Alert
+	for _, minutes := range []int{0, 2, 9, 10, 15, 20} {
+		alert.TriggeredAt = startsAt.Add(time.Duration(minutes) * time.Minute)
+		reporter.report(alert)
+		if minutes%10 == 0 {
+			expected = append(expected, *alert)
+		}
+	}
+
+	if !reflect.DeepEqual(expected, notifier.alerts) {
+		t.Fatal("unexpected alerts notified", "expected", expected, "actual", notifier.alerts)
+	}
+}
+
+func TestLogMonitor_CheckTrafficLoad_Pending(t *testing.T) {
+	timetest.FreezeTime()
+	defer timetest.UnfreezeTime()
+
+	now := time.Date(2006, 01, 02, 15, 04, 05, 000, time.UTC)
+
+	m := setupLogMonitor(t)
+	m.PendingDuration = 2 * time.Minute
+
+	// the condition starts to be met
+	alert := m.CheckTrafficLoad(10000, time.Minute, 100)
+	if alert == nil || alert.State != AlertPending || !alert.ActiveAt.Equal(now) {
+		t.Fatal("unexpected alert", "expected", AlertPending, "actual", alert)
+	}
+
+	// the condition is met for less than the pending duration
+	m.LastAlert.ActiveAt = now.Add(-1 * time.Minute)
+	alert = m.CheckTrafficLoad(12000, time.Minute, 100)
+	if alert == nil || alert.State != AlertPending || alert.Hits != 12000 || !alert.StartsAt.IsZero() {
+		t.Fatal("unexpected alert", "expected", AlertPending, "actual", alert)
+	}
+
+	// the condition is met long enough to fire
+	m.LastAlert.ActiveAt = now.Add(-2 * time.Minute)
+	pending := m.LastAlert
+	alert = m.CheckTrafficLoad(12000, time.Minute, 100)
+	if alert == nil || alert.State != AlertFiring || !alert.ActiveAt.Equal(now.Add(-2*time.Minute)) || !alert.StartsAt.Equal(now) {
+		t.Fatal("unexpected alert", "expected", AlertFiring, "actual", alert)
+	}
+	if alert != pending {
+		t.Fatal("unexpected new alert for the ongoing incident", "expected", pending, "actual", alert)
+	}
+}
+
+func TestAlertReporter_Journal(t *testing.T) {
+	journal, cleanup := setupJournal(t)
+	defer cleanup()
+
+	notifier := &alertRecorder{}
+	reporter := newAlertReporter(notifier, journal, 0)
+
+	pending := &Alert{Name: HighTrafficAlert, State: AlertPending, Hits: 10000}
+	pendingMoreHits := &Alert{Name: HighTrafficAlert, State: AlertPending, Hits: 12000}
+	firing := &Alert{Name: HighTrafficAlert, State: AlertFiring, Hits: 12000}
+	resolved := &Alert{Name: HighTrafficAlert, State: AlertResolved, Hits: 100}
+
+	for _, alert := range []*Alert{pending, pendingMoreHits, firing, firing, resolved} {
+		reporter.report(alert)
+	}
+
+	// transitions recorded
+	alerts, err := journal.Alerts(JournalFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Alert{*pending, *firing, *resolved}
+	if !reflect.DeepEqual(expected, alerts) {
+		t.Fatal("unexpected alerts recorded", "expected", expected, "actual", alerts)
+	}
+
+	// pending alerts are not notified
+	expected = []Alert{*firing, *resolved}
+	if !reflect.DeepEqual(expected, notifier.alerts) {
+		t.Fatal("unexpected alerts notified", "expected", expected, "actual", notifier.alerts)
+	}
 }
@@ -0,0 +1,48 @@
+package metric
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock tells the current time of the metrics
+type Clock interface {
+	Now() time.Time
+}
+
+// SystemClock is the wall clock, used to monitor the logs while they are written
+var SystemClock Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// EventClock is a clock set by the events, used to analyze the logs at the time of their events
+type EventClock struct {
+	sync.RWMutex
+	now time.Time
+}
+
+func NewEventClock() *EventClock {
+	return &EventClock{}
+}
+
+// Now returns the time of the most recent event
+func (c *EventClock) Now() time.Time {
+	c.RLock()
+	defer c.RUnlock()
+
+	return c.now
+}
+
+// Advance moves the clock to the date, the clock never goes back in time
+func (c *EventClock) Advance(date time.Time) {
+	c.Lock()
+	defer c.Unlock()
+
+	if date.After(c.now) {
+		c.now = date
+	}
+}
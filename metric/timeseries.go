@@ -9,18 +9,24 @@ import (
 type TimeSeries struct {
 	sync.RWMutex
 	series map[time.Time]int64
+	clock  Clock
 }
 
 func NewTimeSeries() *TimeSeries {
+	return NewTimeSeriesWithClock(SystemClock)
+}
+
+// NewTimeSeriesWithClock creates a time series ignoring the dates after the time of the clock
+func NewTimeSeriesWithClock(clock Clock) *TimeSeries {
 	return &TimeSeries{
 		series: map[time.Time]int64{},
+		clock:  clock,
 	}
 }
 
-// Increments the time counter value
+// Increments the time counter value, the dates in the future are ignored
 func (t *TimeSeries) Inc(date time.Time, value int64) {
-	// todo remove time.now() before release it
-	if date.After(time.Now()) {
+	if date.After(t.clock.Now()) {
 		return
 	}
 
@@ -40,10 +46,10 @@ func (t *TimeSeries) CountSince(since time.Time) int64 {
 	t.RLock()
 	defer t.RUnlock()
 
+	now := t.clock.Now()
 	var total int64
 	for date, hits := range t.series {
-		// todo remove time.now() before release it
-		if date.Before(since) || date.After(time.Now()) {
+		if date.Before(since) || date.After(now) {
 			continue
 		}
 		total += hits
@@ -70,3 +76,84 @@ func (t *TimeSeries) Clean(before time.Time) int64 {
 	t.series = newSeries
 	return cleaned
 }
+
+// len returns the number of buckets of time stored
+func (t *TimeSeries) len() int {
+	t.RLock()
+	defer t.RUnlock()
+
+	return len(t.series)
+}
+
+// TimeSeriesVec is a collection of time series identified by a label
+type TimeSeriesVec struct {
+	sync.RWMutex
+	series map[string]*TimeSeries
+	clock  Clock
+}
+
+func NewTimeSeriesVec() *TimeSeriesVec {
+	return NewTimeSeriesVecWithClock(SystemClock)
+}
+
+// NewTimeSeriesVecWithClock creates a collection of time series ignoring the dates after the time of the clock
+func NewTimeSeriesVecWithClock(clock Clock) *TimeSeriesVec {
+	return &TimeSeriesVec{
+		series: make(map[string]*TimeSeries),
+		clock:  clock,
+	}
+}
+
+// Increments the time counter value of the series specified by its label
+func (t *TimeSeriesVec) Inc(label string, date time.Time, value int64) {
+	t.Lock()
+	ts, ok := t.series[label]
+	if !ok {
+		ts = NewTimeSeriesWithClock(t.clock)
+		t.series[label] = ts
+	}
+	t.Unlock()
+
+	ts.Inc(date, value)
+}
+
+// Sums the total of counters since a date for the series specified by its label
+func (t *TimeSeriesVec) CountSince(label string, since time.Time) int64 {
+	t.RLock()
+	defer t.RUnlock()
+
+	ts, ok := t.series[label]
+	if !ok {
+		return 0
+	}
+	return ts.CountSince(since)
+}
+
+// Sums the total of counters since a date for all the series
+func (t *TimeSeriesVec) AllCountsSince(since time.Time) map[string]int64 {
+	t.RLock()
+	defer t.RUnlock()
+
+	counts := make(map[string]int64)
+	for label, ts := range t.series {
+		counts[label] = ts.CountSince(since)
+	}
+
+	return counts
+}
+
+// Cleans older time series and removes the series which became empty
+func (t *TimeSeriesVec) Clean(before time.Time) int64 {
+	t.Lock()
+	defer t.Unlock()
+
+	var cleaned int64
+	for label, ts := range t.series {
+		cleaned += ts.Clean(before)
+		if ts.len() == 0 {
+			delete(t.series, label)
+		}
+	}
+
+	return cleaned
+}
@@ -1,6 +1,7 @@
 package metric_test
 
 import (
+	"reflect"
 	"testing"
 	"time"
 
@@ -89,3 +90,116 @@ func setupTimeSeries(t *testing.T, starDate time.Time) *metric.TimeSeries {
 
 	return ts
 }
+
+func TestTimeSeriesVec_CountSince(t *testing.T) {
+	type testCase struct {
+		Label          string
+		Date           time.Time
+		Increment      int64
+		CountSince     time.Time
+		ExpectedCount  int64
+		ExpectedCounts map[string]int64
+	}
+
+	startDate := time.Date(2020, 02, 20, 10, 25, 32, 0, time.UTC)
+
+	cases := map[string]testCase{
+		"new label": {
+			Label:         "checkout",
+			Date:          startDate.Add(55 * time.Second),
+			Increment:     3,
+			CountSince:    startDate.Add(25 * time.Second),
+			ExpectedCount: 3,
+			ExpectedCounts: map[string]int64{
+				"login":    3,
+				"checkout": 3,
+			},
+		},
+		"existing label": {
+			Label:         "login",
+			Date:          startDate.Add(30 * time.Second),
+			Increment:     5,
+			CountSince:    startDate.Add(25 * time.Second),
+			ExpectedCount: 8,
+			ExpectedCounts: map[string]int64{
+				"login": 8,
+			},
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			tsv := setupTimeSeriesVec(t, startDate)
+			tsv.Inc(c.Label, c.Date, c.Increment)
+
+			actual := tsv.CountSince(c.Label, c.CountSince)
+			if c.ExpectedCount != actual {
+				t.Fatal("unexpected count", "expected", c.ExpectedCount, "actual", actual)
+			}
+
+			actualCounts := tsv.AllCountsSince(c.CountSince)
+			if !reflect.DeepEqual(c.ExpectedCounts, actualCounts) {
+				t.Fatal("unexpected counts", "expected", c.ExpectedCounts, "actual", actualCounts)
+			}
+		})
+	}
+}
+
+func TestTimeSeriesVec_Clean(t *testing.T) {
+	startDate := time.Date(2020, 02, 20, 10, 25, 32, 0, time.UTC)
+
+	tsv := setupTimeSeriesVec(t, startDate)
+	tsv.Inc("checkout", startDate.Add(5*time.Second), 4)
+
+	cleaned := tsv.Clean(startDate.Add(25 * time.Second))
+	if cleaned != 3 {
+		t.Fatal("unexpected cleaned", "expected", 3, "actual", cleaned)
+	}
+
+	expected := map[string]int64{"login": 3}
+	actual := tsv.AllCountsSince(startDate)
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatal("unexpected remaining", "expected", expected, "actual", actual)
+	}
+}
+
+func setupTimeSeriesVec(t *testing.T, starDate time.Time) *metric.TimeSeriesVec {
+	tsv := metric.NewTimeSeriesVec()
+	incDate := starDate
+
+	for i := 1; i <= 5; i++ {
+		incDate = incDate.Add(10 * time.Second)
+		tsv.Inc("login", incDate, 1)
+	}
+
+	return tsv
+}
+
+func TestTimeSeries_EventClock(t *testing.T) {
+	startDate := time.Date(2020, 02, 20, 10, 25, 32, 0, time.UTC)
+
+	clock := metric.NewEventClock()
+	clock.Advance(startDate)
+	ts := metric.NewTimeSeriesWithClock(clock)
+	ts.Inc(startDate.Add(-10*time.Second), 2)
+	ts.Inc(startDate.Add(10*time.Second), 3)
+
+	// the dates after the time of the clock are ignored
+	actual := ts.CountSince(startDate.Add(-time.Minute))
+	if actual != 2 {
+		t.Fatal("unexpected count", "expected", 2, "actual", actual)
+	}
+
+	// the clock never goes back in time
+	clock.Advance(startDate.Add(time.Minute))
+	clock.Advance(startDate)
+	if !clock.Now().Equal(startDate.Add(time.Minute)) {
+		t.Fatal("unexpected clock", "expected", startDate.Add(time.Minute), "actual", clock.Now())
+	}
+
+	ts.Inc(startDate.Add(10*time.Second), 3)
+	actual = ts.CountSince(startDate.Add(-time.Minute))
+	if actual != 5 {
+		t.Fatal("unexpected count", "expected", 5, "actual", actual)
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// AlertNotifier sends the alert transitions (firing and resolved) to an external system
+type AlertNotifier interface {
+	// Notify sends the alert, it must not block the monitoring
+	Notify(alert Alert) error
+
+	// Close stops the notifier once the pending alerts are sent
+	Close() error
+}
+
+// MultiNotifier sends the alerts to several notifiers
+type MultiNotifier []AlertNotifier
+
+func (m MultiNotifier) Notify(alert Alert) error {
+	var errs []string
+	for _, notifier := range m {
+		if err := notifier.Notify(alert); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("cannot notify alert: %s", strings.Join(errs, " - "))
+	}
+	return nil
+}
+
+func (m MultiNotifier) Close() error {
+	var errs []string
+	for _, notifier := range m {
+		if err := notifier.Close(); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("cannot close notifiers: %s", strings.Join(errs, " - "))
+	}
+	return nil
+}
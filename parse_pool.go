@@ -0,0 +1,327 @@
+package main
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/ali.ghanem/http-log-monitoring/commonlog"
+	"github.com/ali.ghanem/http-log-monitoring/metric"
+)
+
+// Default values of the parse workers
+const (
+	defaultParseWorkers   = 1
+	defaultParseQueueSize = 1000
+	defaultSampleRate     = 10
+)
+
+// overflowReportInterval is the minimum interval between two messages about the queue becoming full
+const overflowReportInterval = 10 * time.Second
+
+// Policies applied when the lines arrive faster than they are handled
+const (
+	BlockPolicy      = "block"       // the reading of the logs waits for the queue
+	DropOldestPolicy = "drop-oldest" // the oldest line of the queue is dropped for the new one
+	SamplePolicy     = "sample"      // one line out of the sample rate is kept while the queue is full
+)
+
+// ParseConfig is the configuration of the workers parsing the log lines
+type ParseConfig struct {
+	Workers        int    // Number of lines parsed at the same time
+	QueueSize      int    // Number of lines waiting for a worker, and of parsed lines waiting to be handled
+	Unordered      bool   // Hands off the lines as soon as they are parsed instead of in the order they were read
+	OverflowPolicy string // Policy applied when the queue is full, BlockPolicy by default
+	SampleRate     int    // One line out of SampleRate is kept by the SamplePolicy
+}
+
+// parsedLine is a log line with the event parsed from it
+type parsedLine struct {
+	line   LogLine
+	event  commonlog.Event
+	err    error // error of the parser, the errors of the sources are in the line
+	weight int64 // number of lines read that the line stands for when the lines are sampled
+
+	dropped bool // the line has been dropped from the queue by the overflow policy
+}
+
+// parseJob is a line dispatched to the workers,
+// its result is sent to its own channel when the pool is ordered
+type parseJob struct {
+	line   LogLine
+	weight int64
+	result chan parsedLine
+}
+
+// parsePool parses the lines of a source with several workers.
+// The lines are dispatched in the order they are read and unwrapped from their envelope on the way.
+// At most QueueSize lines are being parsed or waiting for their turn, and QueueSize parsed lines wait for
+// the monitor: once both are full, the overflow policy decides whether the reading waits or lines are shed.
+// When the pool is ordered, the lines are handed off in the order they were read whatever the worker parsing them.
+type parsePool struct {
+	config   ParseConfig
+	parse    commonlog.Parser
+	envelope *envelopeDecoder
+
+	slots   chan struct{} // lines dispatched and not handed off yet
+	jobs    chan parseJob
+	pending chan chan parsedLine // results of the ordered jobs, in the order of the lines
+	results chan parsedLine
+
+	dropped *metric.Counter
+	sampled *metric.Counter
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// newParsePool creates a pool parsing the lines with the parser, the envelope decoder is optional
+func newParsePool(config ParseConfig, parse commonlog.Parser, envelope *envelopeDecoder) *parsePool {
+	if config.Workers == 0 {
+		config.Workers = defaultParseWorkers
+	}
+	if config.QueueSize == 0 {
+		config.QueueSize = defaultParseQueueSize
+	}
+	if len(config.OverflowPolicy) == 0 {
+		config.OverflowPolicy = BlockPolicy
+	}
+	if config.SampleRate == 0 {
+		config.SampleRate = defaultSampleRate
+	}
+
+	return &parsePool{
+		config:   config,
+		parse:    parse,
+		envelope: envelope,
+		slots:    make(chan struct{}, config.QueueSize),
+		jobs:     make(chan parseJob, config.QueueSize),
+		pending:  make(chan chan parsedLine, config.QueueSize),
+		results:  make(chan parsedLine, config.QueueSize),
+		dropped:  metric.NewCounter(),
+		sampled:  metric.NewCounter(),
+		stop:     make(chan struct{}),
+	}
+}
+
+// Start parses the lines until the channel is closed or the pool is stopped,
+// the results are closed once the lines dispatched have been handed off
+func (p *parsePool) Start(lines <-chan LogLine) {
+	var handOff sync.WaitGroup
+	handOff.Add(p.config.Workers)
+	for i := 0; i < p.config.Workers; i++ {
+		go func() {
+			defer handOff.Done()
+			p.work()
+		}()
+	}
+
+	if !p.config.Unordered {
+		handOff.Add(1)
+		go func() {
+			defer handOff.Done()
+			p.collect()
+		}()
+	}
+
+	go p.dispatch(lines)
+
+	go func() {
+		handOff.Wait()
+		close(p.results)
+	}()
+}
+
+// Results returns the parsed lines, the channel is closed when there are no more lines
+func (p *parsePool) Results() <-chan parsedLine {
+	return p.results
+}
+
+// Stop stops reading the lines
+func (p *parsePool) Stop() {
+	p.stopOnce.Do(func() {
+		close(p.stop)
+	})
+}
+
+// Depth returns the number of lines read and not handled yet by the monitor
+func (p *parsePool) Depth() int {
+	return len(p.slots) + len(p.results)
+}
+
+// Capacity returns the maximum number of lines read and not handled yet by the monitor
+func (p *parsePool) Capacity() int {
+	return cap(p.slots) + cap(p.results)
+}
+
+// Dropped returns the number of lines dropped by the overflow policy
+func (p *parsePool) Dropped() int64 {
+	return p.dropped.Value()
+}
+
+// Sampled returns the number of lines skipped by the sampling, they are counted through the weight of the lines kept
+func (p *parsePool) Sampled() int64 {
+	return p.sampled.Value()
+}
+
+// dispatch sends the complete lines to the workers according to the overflow policy
+func (p *parsePool) dispatch(lines <-chan LogLine) {
+	defer close(p.jobs)
+	defer close(p.pending)
+
+	var overloaded bool      // the queue has been reported full
+	var reportedAt time.Time // time the queue was last reported full
+	var skipped int64
+	for {
+		var line LogLine
+		var ok bool
+		select {
+		case <-p.stop:
+			return
+		case line, ok = <-lines:
+			if !ok {
+				return
+			}
+		}
+
+		if p.envelope != nil {
+			var complete bool
+			line, complete = p.envelope.decode(line)
+			if !complete {
+				continue
+			}
+		}
+
+		// a free slot does not need the overflow policy
+		acquired := false
+		select {
+		case p.slots <- struct{}{}:
+			acquired = true
+		default:
+		}
+		// a saturated queue is full again right after each line handed off, so the queue is reported full
+		// once by interval and it has room again once half of it is free
+		switch {
+		case !acquired && !overloaded && time.Since(reportedAt) >= overflowReportInterval:
+			overloaded = true
+			reportedAt = time.Now()
+			log.Println("parse queue is full", "depth", p.Depth(), "policy", p.config.OverflowPolicy)
+		case acquired && overloaded && p.Depth() <= p.Capacity()/2:
+			overloaded = false
+			log.Println("parse queue is no longer full", "depth", p.Depth(), "dropped", p.Dropped(), "sampled", p.Sampled())
+		}
+
+		if !acquired {
+			switch p.config.OverflowPolicy {
+			case DropOldestPolicy:
+				p.dropOldest()
+			case SamplePolicy:
+				if (skipped+1)%int64(p.config.SampleRate) != 0 {
+					skipped++
+					p.sampled.Inc(1)
+					continue
+				}
+			}
+
+			select {
+			case <-p.stop:
+				return
+			case p.slots <- struct{}{}:
+			}
+		}
+
+		job := parseJob{line: line, weight: skipped + 1}
+		skipped = 0
+		if !p.config.Unordered {
+			job.result = make(chan parsedLine, 1)
+		}
+		select {
+		case <-p.stop:
+			return
+		case p.jobs <- job:
+		}
+
+		if job.result != nil {
+			select {
+			case <-p.stop:
+				return
+			case p.pending <- job.result:
+			}
+		}
+	}
+}
+
+// dropOldest drops the oldest line waiting for the monitor, or the oldest line waiting for a worker
+// when the monitor is keeping up and the parsing is late. Nothing is dropped when all the lines are being parsed.
+func (p *parsePool) dropOldest() {
+	select {
+	case <-p.results:
+		p.dropped.Inc(1)
+		return
+	default:
+	}
+
+	select {
+	case job := <-p.jobs:
+		p.dropped.Inc(1)
+		<-p.slots
+		if job.result != nil {
+			job.result <- parsedLine{line: job.line, dropped: true}
+		}
+	default:
+	}
+}
+
+// work parses the lines dispatched, it drains the jobs even when the pool is stopped
+// so the collector is never left waiting for a result
+func (p *parsePool) work() {
+	for job := range p.jobs {
+		result := p.parseLine(job.line)
+		result.weight = job.weight
+		if job.result != nil {
+			job.result <- result
+			continue
+		}
+
+		select {
+		case <-p.stop:
+		case p.results <- result:
+			<-p.slots
+		}
+	}
+}
+
+// collect hands off the results of the ordered jobs in the order of the lines
+func (p *parsePool) collect() {
+	for pending := range p.pending {
+		result := <-pending
+		if result.dropped {
+			continue
+		}
+
+		select {
+		case <-p.stop:
+			return
+		case p.results <- result:
+			<-p.slots
+		}
+	}
+}
+
+// parseLine returns the event of the line, the pre-parsed events and the read errors are handed off as they are
+func (p *parsePool) parseLine(line LogLine) parsedLine {
+	switch {
+	case line.Err != nil:
+		return parsedLine{line: line}
+	case line.Event != nil:
+		return parsedLine{line: line, event: *line.Event}
+	}
+
+	event, err := p.parse(line.Text)
+	if err != nil {
+		return parsedLine{line: line, err: err}
+	}
+	event.Source = line.Source
+	event.Labels = line.Labels
+	return parsedLine{line: line, event: event}
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/ali.ghanem/http-log-monitoring/commonlog"
+)
+
+// poolLine returns a line of the common format with a distinct host
+func poolLine(n int) string {
+	return fmt.Sprintf(`66.%v.220.245 - - [21/Feb/2020:17:35:21 +0100] "POST /technologies/e-enable/collaborative/bandwidth HTTP/1.0" 200 19072`, n)
+}
+
+func TestParsePool_Results(t *testing.T) {
+	type testCase struct {
+		Config ParseConfig
+	}
+
+	cases := map[string]testCase{
+		"single worker": {
+			Config: ParseConfig{Workers: 1, QueueSize: 1},
+		},
+		"ordered workers": {
+			Config: ParseConfig{Workers: 4, QueueSize: 2},
+		},
+		"unordered workers": {
+			Config: ParseConfig{Workers: 4, QueueSize: 2, Unordered: true},
+		},
+	}
+
+	readErr := errors.New("cannot read")
+	preParsed := commonlog.Event{Host: "ingest", Section: "api"}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			lines := make(chan LogLine)
+			go func() {
+				defer close(lines)
+				for i := 0; i < 100; i++ {
+					lines <- LogLine{Source: "access.log", Text: poolLine(i), Position: FilePosition{Offset: int64(i + 1)}}
+				}
+				lines <- LogLine{Source: "access.log", Text: "not a log line"}
+				lines <- LogLine{Source: "access.log", Err: readErr}
+				lines <- LogLine{Source: "http", Event: &preParsed}
+			}()
+
+			pool := newParsePool(c.Config, commonlog.Parse, nil)
+			pool.Start(lines)
+			defer pool.Stop()
+
+			var hosts []string
+			var parseErrors, readErrors, events int
+			for result := range pool.Results() {
+				switch {
+				case result.line.Err != nil:
+					readErrors++
+				case result.err != nil:
+					parseErrors++
+				case result.line.Event != nil:
+					events++
+				default:
+					if result.event.Source != "access.log" {
+						t.Fatal("unexpected source", "expected", "access.log", "actual", result.event.Source)
+					}
+					hosts = append(hosts, result.event.Host)
+				}
+			}
+
+			if readErrors != 1 || parseErrors != 1 || events != 1 {
+				t.Fatal("unexpected results", "expected", 1, 1, 1, "actual", readErrors, parseErrors, events)
+			}
+
+			expected := make([]string, 100)
+			for i := range expected {
+				expected[i] = fmt.Sprintf("66.%v.220.245", i)
+			}
+			if c.Config.Unordered {
+				sort.Strings(expected)
+				sort.Strings(hosts)
+			}
+			if !reflect.DeepEqual(expected, hosts) {
+				t.Fatal("unexpected hosts", "expected", expected, "actual", hosts)
+			}
+		})
+	}
+}
+
+func TestParsePool_Stop(t *testing.T) {
+	lines := make(chan LogLine)
+	pool := newParsePool(ParseConfig{Workers: 2, QueueSize: 1}, commonlog.Parse, nil)
+	pool.Start(lines)
+
+	// the results are not consumed so the pool is blocked once its queues are full
+	go func() {
+		for i := 0; ; i++ {
+			select {
+			case lines <- LogLine{Text: poolLine(i)}:
+			case <-pool.stop:
+				return
+			}
+		}
+	}()
+
+	pool.Stop()
+	for range pool.Results() {
+	}
+}
+
+func TestParsePool_Overflow(t *testing.T) {
+	type testCase struct {
+		Config          ParseConfig
+		ExpectedDropped bool
+		ExpectedSampled bool
+	}
+
+	cases := map[string]testCase{
+		"drop oldest ordered": {
+			Config:          ParseConfig{Workers: 2, QueueSize: 4, OverflowPolicy: DropOldestPolicy},
+			ExpectedDropped: true,
+		},
+		"drop oldest unordered": {
+			Config:          ParseConfig{Workers: 2, QueueSize: 4, OverflowPolicy: DropOldestPolicy, Unordered: true},
+			ExpectedDropped: true,
+		},
+		"sample": {
+			Config:          ParseConfig{Workers: 2, QueueSize: 4, OverflowPolicy: SamplePolicy, SampleRate: 5},
+			ExpectedSampled: true,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			lines := make(chan LogLine)
+			pool := newParsePool(c.Config, commonlog.Parse, nil)
+			pool.Start(lines)
+			defer pool.Stop()
+
+			go func() {
+				defer close(lines)
+				for i := 0; i < 100; i++ {
+					lines <- LogLine{Text: poolLine(i)}
+				}
+			}()
+
+			// the results are consumed once the queue overflowed, the kept lines of the sampling still wait for the queue
+			deadline := time.Now().Add(5 * time.Second)
+			for pool.Dropped()+pool.Sampled() == 0 {
+				if time.Now().After(deadline) {
+					t.Fatal("unexpected queue never full")
+				}
+				time.Sleep(time.Millisecond)
+			}
+
+			var kept, weights int64
+			for result := range pool.Results() {
+				if result.err != nil {
+					t.Fatal(result.err)
+				}
+				kept++
+				weights += result.weight
+			}
+
+			if (pool.Dropped() > 0) != c.ExpectedDropped || (pool.Sampled() > 0) != c.ExpectedSampled {
+				t.Fatal("unexpected lines shed", "dropped", pool.Dropped(), "sampled", pool.Sampled())
+			}
+			if kept+pool.Dropped()+pool.Sampled() != 100 {
+				t.Fatal("unexpected lines", "expected", 100, "actual", kept, pool.Dropped(), pool.Sampled())
+			}
+			// the lines sampled at the end of the log have no kept line to be counted with
+			if c.ExpectedSampled && (weights > 100 || weights <= int64(100-c.Config.SampleRate)) {
+				t.Fatal("unexpected weights", "expected", 100, "actual", weights)
+			}
+			if !c.ExpectedSampled && weights != kept {
+				t.Fatal("unexpected weights", "expected", kept, "actual", weights)
+			}
+			if pool.Depth() != 0 || pool.Capacity() != 2*c.Config.QueueSize {
+				t.Fatal("unexpected queue", "expected", 0, 2*c.Config.QueueSize, "actual", pool.Depth(), pool.Capacity())
+			}
+		})
+	}
+}
+
+func BenchmarkParsePool(b *testing.B) {
+	for _, unordered := range []bool{false, true} {
+		for _, workers := range []int{1, 2, 4, 8} {
+			name := fmt.Sprintf("ordered/%v workers", workers)
+			if unordered {
+				name = fmt.Sprintf("unordered/%v workers", workers)
+			}
+
+			b.Run(name, func(b *testing.B) {
+				lines := make(chan LogLine, defaultParseQueueSize)
+				go func() {
+					defer close(lines)
+					for n := 0; n < b.N; n++ {
+						lines <- LogLine{Text: poolLine(n)}
+					}
+				}()
+
+				pool := newParsePool(ParseConfig{Workers: workers, Unordered: unordered}, commonlog.Parse, nil)
+				pool.Start(lines)
+				defer pool.Stop()
+
+				for result := range pool.Results() {
+					if result.err != nil {
+						b.Fatal(result.err)
+					}
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"errors"
+	"time"
+)
+
+// errReplayStopped is returned when the replay is interrupted before the end of the logs
+var errReplayStopped = errors.New("replay stopped")
+
+// pacer delays the events of a replay so they are handled at the pace of their dates.
+// The delays are computed from the first event so the time spent handling the events does not accumulate.
+type pacer struct {
+	speed float64 // multiplier of the pace of the events, 0 or less to replay them as fast as possible
+
+	now   func() time.Time         // wall clock
+	sleep func(time.Duration) bool // waits unless the replay is stopped
+	stop  <-chan struct{}          // closed to stop the replay
+
+	start time.Time // wall time of the first event
+	first time.Time // date of the first event
+}
+
+func newPacer(speed float64) *pacer {
+	p := &pacer{speed: speed, now: time.Now}
+	p.sleep = p.wait
+	return p
+}
+
+// pace waits until the event of the date must be handled, it returns false when the replay is stopped
+func (p *pacer) pace(date time.Time) bool {
+	if p.first.IsZero() {
+		p.first = date
+		p.start = p.now()
+	}
+
+	var delay time.Duration
+	if p.speed > 0 {
+		elapsed := time.Duration(float64(date.Sub(p.first)) / p.speed)
+		delay = elapsed - p.now().Sub(p.start)
+	}
+	if delay <= 0 {
+		select {
+		case <-p.stop:
+			return false
+		default:
+			return true
+		}
+	}
+
+	return p.sleep(delay)
+}
+
+// wait waits for the delay unless the replay is stopped
+func (p *pacer) wait(delay time.Duration) bool {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-p.stop:
+		return false
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPacer_Pace(t *testing.T) {
+	type testCase struct {
+		Speed          float64
+		Handling       time.Duration // wall time spent handling each event
+		ExpectedDelays []time.Duration
+	}
+
+	cases := map[string]testCase{
+		"original speed": {
+			Speed:          1,
+			ExpectedDelays: []time.Duration{10 * time.Second, 20 * time.Second},
+		},
+		"accelerated": {
+			Speed:          10,
+			ExpectedDelays: []time.Duration{time.Second, 2 * time.Second},
+		},
+		"handling time deducted": {
+			Speed:          10,
+			Handling:       500 * time.Millisecond,
+			ExpectedDelays: []time.Duration{500 * time.Millisecond, 1500 * time.Millisecond},
+		},
+		"as fast as possible": {
+			Speed:          0,
+			ExpectedDelays: nil,
+		},
+	}
+
+	start := time.Date(2018, 5, 9, 16, 0, 0, 0, time.UTC)
+	dates := []time.Time{start, start.Add(10 * time.Second), start.Add(30 * time.Second), start.Add(20 * time.Second)}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			wall := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+			var delays []time.Duration
+
+			p := newPacer(c.Speed)
+			p.now = func() time.Time { return wall }
+			p.sleep = func(delay time.Duration) bool {
+				delays = append(delays, delay)
+				wall = wall.Add(delay)
+				return true
+			}
+
+			for _, date := range dates {
+				if !p.pace(date) {
+					t.Fatal("unexpected stop")
+				}
+				wall = wall.Add(c.Handling)
+			}
+
+			if !reflect.DeepEqual(c.ExpectedDelays, delays) {
+				t.Fatal("unexpected delays", "expected", c.ExpectedDelays, "actual", delays)
+			}
+		})
+	}
+}
+
+func TestPacer_Stop(t *testing.T) {
+	stop := make(chan struct{})
+	p := newPacer(1)
+	p.stop = stop
+
+	start := time.Now()
+	if !p.pace(start) {
+		t.Fatal("unexpected stop of the first event")
+	}
+
+	close(stop)
+	stopped := make(chan bool)
+	go func() {
+		stopped <- p.pace(start.Add(time.Hour))
+	}()
+
+	select {
+	case paced := <-stopped:
+		if paced {
+			t.Fatal("unexpected event paced after the stop")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("replay not stopped")
+	}
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Silence mutes the notifications of the alerts matching its labels during a time range
+type Silence struct {
+	ID       string            `json:"id"`
+	Matchers map[string]string `json:"matchers"` // Labels of the alerts to silence: alertname, severity, section or host
+	StartsAt time.Time         `json:"starts_at"`
+	EndsAt   time.Time         `json:"ends_at"`
+	Comment  string            `json:"comment"`
+}
+
+// MaintenanceWindow mutes the notifications of the alerts matching its labels periodically
+type MaintenanceWindow struct {
+	Matchers map[string]string `json:"matchers"` // Labels of the alerts to silence: alertname, severity, section or host
+	Schedule string            `json:"schedule"` // Cron expression of the window start: minute hour day-of-month month day-of-week
+	Duration string            `json:"duration"` // Duration of the window, like "2h"
+	Comment  string            `json:"comment"`
+
+	schedule *cronSchedule
+	duration time.Duration
+}
+
+// Silencer stores the silences and the maintenance windows
+type Silencer struct {
+	sync.RWMutex
+	silences map[string]Silence
+	windows  []MaintenanceWindow
+}
+
+// silencesFile is the format of the file declaring the silences
+type silencesFile struct {
+	Silences           []Silence           `json:"silences"`
+	MaintenanceWindows []MaintenanceWindow `json:"maintenance_windows"`
+}
+
+func NewSilencer() *Silencer {
+	return &Silencer{
+		silences: make(map[string]Silence),
+	}
+}
+
+// LoadSilencer creates a silencer with the silences and maintenance windows declared in a JSON file
+func LoadSilencer(path string) (*Silencer, error) {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read silences file: %w", err)
+	}
+
+	var file silencesFile
+	err = json.Unmarshal(raw, &file)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse silences file: %w", err)
+	}
+
+	s := NewSilencer()
+	for _, silence := range file.Silences {
+		_, err = s.Add(silence)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, window := range file.MaintenanceWindows {
+		err = s.AddMaintenanceWindow(window)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// Add stores a silence and returns it with its id, the silence starts now when its start is not set
+func (s *Silencer) Add(silence Silence) (Silence, error) {
+	if len(silence.Matchers) == 0 {
+		return silence, errors.New("silence without matchers")
+	}
+	if silence.StartsAt.IsZero() {
+		silence.StartsAt = time.Now()
+	}
+	if !silence.EndsAt.After(silence.StartsAt) {
+		return silence, fmt.Errorf("silence ends before it starts: %s - %s", silence.StartsAt, silence.EndsAt)
+	}
+
+	if len(silence.ID) == 0 {
+		id, err := newSilenceID()
+		if err != nil {
+			return silence, err
+		}
+		silence.ID = id
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	s.silences[silence.ID] = silence
+	return silence, nil
+}
+
+// Delete removes a silence and returns whether it existed
+func (s *Silencer) Delete(id string) bool {
+	s.Lock()
+	defer s.Unlock()
+
+	_, ok := s.silences[id]
+	delete(s.silences, id)
+	return ok
+}
+
+// Silences returns the silences which are not expired sorted by start
+func (s *Silencer) Silences() []Silence {
+	s.RLock()
+	defer s.RUnlock()
+
+	now := time.Now()
+	silences := make([]Silence, 0, len(s.silences))
+	for _, silence := range s.silences {
+		if silence.EndsAt.Before(now) {
+			continue
+		}
+		silences = append(silences, silence)
+	}
+	sort.Slice(silences, func(i, j int) bool {
+		return silences[i].StartsAt.Before(silences[j].StartsAt)
+	})
+
+	return silences
+}
+
+// AddMaintenanceWindow stores a recurring maintenance window
+func (s *Silencer) AddMaintenanceWindow(window MaintenanceWindow) error {
+	if len(window.Matchers) == 0 {
+		return errors.New("maintenance window without matchers")
+	}
+
+	var err error
+	window.schedule, err = parseCron(window.Schedule)
+	if err != nil {
+		return err
+	}
+
+	window.duration, err = time.ParseDuration(window.Duration)
+	if err != nil {
+		return fmt.Errorf("invalid maintenance window duration: %w", err)
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	s.windows = append(s.windows, window)
+	return nil
+}
+
+// MaintenanceWindows returns the maintenance windows
+func (s *Silencer) MaintenanceWindows() []MaintenanceWindow {
+	s.RLock()
+	defer s.RUnlock()
+
+	return append([]MaintenanceWindow(nil), s.windows...)
+}
+
+// IsSilenced checks if an alert with the labels is muted by a silence or a maintenance window at a time
+func (s *Silencer) IsSilenced(labels map[string]string, at time.Time) bool {
+	s.RLock()
+	defer s.RUnlock()
+
+	for _, silence := range s.silences {
+		if !at.Before(silence.StartsAt) && at.Before(silence.EndsAt) && matchLabels(silence.Matchers, labels) {
+			return true
+		}
+	}
+
+	for _, window := range s.windows {
+		if matchLabels(window.Matchers, labels) && window.schedule.activeSince(at, window.duration) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchLabels checks if the labels have all the values of the matchers
+func matchLabels(matchers, labels map[string]string) bool {
+	for name, value := range matchers {
+		if labels[name] != value {
+			return false
+		}
+	}
+
+	return true
+}
+
+func newSilenceID() (string, error) {
+	raw := make([]byte, 8)
+	_, err := rand.Read(raw)
+	if err != nil {
+		return "", fmt.Errorf("cannot generate silence id: %w", err)
+	}
+
+	return hex.EncodeToString(raw), nil
+}
+
+// SilencingNotifier does not forward the alerts muted by the silencer.
+// A resolved alert is forwarded only if its firing alert has been forwarded.
+type SilencingNotifier struct {
+	silencer *Silencer
+	next     AlertNotifier
+
+	lock      sync.Mutex
+	forwarded map[string]bool // firing alerts forwarded by fingerprint
+}
+
+func NewSilencingNotifier(silencer *Silencer, next AlertNotifier) *SilencingNotifier {
+	return &SilencingNotifier{
+		silencer:  silencer,
+		next:      next,
+		forwarded: make(map[string]bool),
+	}
+}
+
+func (n *SilencingNotifier) Notify(alert Alert) error {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	fingerprint := alert.Fingerprint()
+	if alert.State == AlertResolved {
+		if !n.forwarded[fingerprint] {
+			log.Println("resolved alert not notified, its firing alert was silenced", "alert", alert.ID())
+			return nil
+		}
+		delete(n.forwarded, fingerprint)
+		return n.next.Notify(alert)
+	}
+
+	if n.silencer.IsSilenced(alert.Labels(), time.Now()) {
+		log.Println("alert silenced", "alert", alert.ID())
+		return nil
+	}
+
+	n.forwarded[fingerprint] = true
+	return n.next.Notify(alert)
+}
+
+func (n *SilencingNotifier) Close() error {
+	return n.next.Close()
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSilencer_IsSilenced(t *testing.T) {
+	now := time.Date(2020, 02, 24, 2, 30, 0, 0, time.UTC)
+
+	silencer := NewSilencer()
+	_, err := silencer.Add(Silence{
+		Matchers: map[string]string{"alertname": HighTrafficAlert, "section": "login"},
+		StartsAt: now.Add(-time.Hour),
+		EndsAt:   now.Add(time.Hour),
+		Comment:  "load test",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = silencer.AddMaintenanceWindow(MaintenanceWindow{
+		Matchers: map[string]string{"alertname": NoDataAlert},
+		Schedule: "0 2 * * *",
+		Duration: "1h",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type testCase struct {
+		Alert    Alert
+		At       time.Time
+		Expected bool
+	}
+
+	cases := map[string]testCase{
+		"silenced section": {
+			Alert:    Alert{Name: HighTrafficAlert, Scope: SectionScope, Key: "login"},
+			At:       now,
+			Expected: true,
+		},
+		"other section": {
+			Alert:    Alert{Name: HighTrafficAlert, Scope: SectionScope, Key: "checkout"},
+			At:       now,
+			Expected: false,
+		},
+		"silence expired": {
+			Alert:    Alert{Name: HighTrafficAlert, Scope: SectionScope, Key: "login"},
+			At:       now.Add(2 * time.Hour),
+			Expected: false,
+		},
+		"during maintenance window": {
+			Alert:    Alert{Name: NoDataAlert},
+			At:       now.Add(24 * time.Hour),
+			Expected: true,
+		},
+		"after maintenance window": {
+			Alert:    Alert{Name: NoDataAlert},
+			At:       now.Add(time.Hour),
+			Expected: false,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			actual := silencer.IsSilenced(c.Alert.Labels(), c.At)
+			if c.Expected != actual {
+				t.Fatal("unexpected silence", "expected", c.Expected, "actual", actual)
+			}
+		})
+	}
+}
+
+func TestLoadSilencer(t *testing.T) {
+	file, err := ioutil.TempFile("", "silences")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+
+	_, err = file.WriteString(`{
+		"silences": [
+			{"matchers": {"host": "10.1.2.3"}, "starts_at": "2020-02-24T02:00:00Z", "ends_at": "2020-02-24T04:00:00Z", "comment": "load test"}
+		],
+		"maintenance_windows": [
+			{"matchers": {"alertname": "low_traffic"}, "schedule": "0 3 * * 0", "duration": "2h", "comment": "weekly deploy"}
+		]
+	}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	silencer, err := LoadSilencer(file.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	at := time.Date(2020, 02, 24, 3, 0, 0, 0, time.UTC)
+	if !silencer.IsSilenced(map[string]string{"alertname": HighTrafficAlert, "host": "10.1.2.3"}, at) {
+		t.Fatal("host not silenced")
+	}
+
+	// sunday
+	at = time.Date(2020, 02, 23, 4, 30, 0, 0, time.UTC)
+	if !silencer.IsSilenced(map[string]string{"alertname": LowTrafficAlert}, at) {
+		t.Fatal("low traffic not silenced during the maintenance")
+	}
+}
+
+func TestSilencingNotifier_Notify(t *testing.T) {
+	silencer := NewSilencer()
+	_, err := silencer.Add(Silence{
+		Matchers: map[string]string{"host": "10.1.2.3"},
+		EndsAt:   time.Now().Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	next := &alertRecorder{}
+	n := NewSilencingNotifier(silencer, next)
+
+	silenced := Alert{Name: HighTrafficAlert, Scope: HostScope, Key: "10.1.2.3", State: AlertFiring}
+	silencedResolved := Alert{Name: HighTrafficAlert, Scope: HostScope, Key: "10.1.2.3", State: AlertResolved}
+	notified := Alert{Name: HighTrafficAlert, Scope: HostScope, Key: "10.1.2.4", State: AlertFiring}
+	notifiedResolved := Alert{Name: HighTrafficAlert, Scope: HostScope, Key: "10.1.2.4", State: AlertResolved}
+
+	for _, alert := range []Alert{silenced, notified, silencedResolved, notifiedResolved} {
+		err = n.Notify(alert)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected := []Alert{notified, notifiedResolved}
+	if !reflect.DeepEqual(expected, next.alerts) {
+		t.Fatal("unexpected alerts notified", "expected", expected, "actual", next.alerts)
+	}
+}
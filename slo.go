@@ -0,0 +1,107 @@
+package main
+
+import (
+	"time"
+
+	"github.com/ali.ghanem/http-log-monitoring/commonlog"
+	"github.com/ali.ghanem/http-log-monitoring/metric"
+)
+
+// DefaultSLOWindow is the rolling window of the error budget
+const DefaultSLOWindow = 30 * 24 * time.Hour
+
+// sloBucket is the precision of the time series of the SLOs,
+// coarse enough to keep the events of the whole window in memory
+const sloBucket = time.Minute
+
+// burnRateRule alerts when the error budget is consumed too fast over a long and a short window,
+// the short window makes the alert resolve quickly once the errors stop
+type burnRateRule struct {
+	Name        string
+	LongWindow  time.Duration
+	ShortWindow time.Duration
+	Threshold   float64 // Burn rate to exceed on both windows
+}
+
+// Multi-window, multi-burn-rate rules of the SRE workbook for a 30 days window:
+// 2% of the budget consumed in 1 hour and 5% of the budget consumed in 6 hours
+var burnRateRules = []burnRateRule{
+	{Name: SLOFastBurnAlert, LongWindow: time.Hour, ShortWindow: 5 * time.Minute, Threshold: 14.4},
+	{Name: SLOSlowBurnAlert, LongWindow: 6 * time.Hour, ShortWindow: 30 * time.Minute, Threshold: 6},
+}
+
+// SLO tracks the availability objective of a section: the ratio of requests without server error
+type SLO struct {
+	Section   string
+	Objective float64 // Ratio of good events to reach, 0.999 for 99.9%
+
+	// Good and Total store the number of good events and of all the events by bucket of time
+	Good  *metric.TimeSeries
+	Total *metric.TimeSeries
+}
+
+// ErrorBudget describes the status of an SLO over its window
+type ErrorBudget struct {
+	Section      string  `json:"section"`
+	Objective    float64 `json:"objective"`
+	Good         int64   `json:"good"`
+	Total        int64   `json:"total"`
+	Availability float64 `json:"availability"` // Ratio of good events, 1 when no event was received
+	Remaining    float64 `json:"remaining"`    // Ratio of the error budget not consumed yet, negative when the budget is exhausted
+}
+
+func NewSLO(section string, objective float64, clock metric.Clock) *SLO {
+	return &SLO{
+		Section:   section,
+		Objective: objective,
+		Good:      metric.NewTimeSeriesWithClock(clock),
+		Total:     metric.NewTimeSeriesWithClock(clock),
+	}
+}
+
+// Record counts the event weight times, it is good when it is not a server error
+func (s *SLO) Record(event commonlog.Event, weight int64) {
+	date := event.Date.Truncate(sloBucket)
+	s.Total.Inc(date, weight)
+	if !IsServerError(event.Status) {
+		s.Good.Inc(date, weight)
+	}
+}
+
+// BurnRate returns how fast the error budget is consumed since a date:
+// 1 consumes exactly the budget over the window, 0 when no event was received
+func (s *SLO) BurnRate(since time.Time) float64 {
+	since = since.Truncate(sloBucket)
+	total := s.Total.CountSince(since)
+	if total == 0 {
+		return 0
+	}
+
+	errorRate := float64(total-s.Good.CountSince(since)) / float64(total)
+	return errorRate / (1 - s.Objective)
+}
+
+// Budget returns the status of the error budget since a date
+func (s *SLO) Budget(since time.Time) ErrorBudget {
+	since = since.Truncate(sloBucket)
+	budget := ErrorBudget{
+		Section:      s.Section,
+		Objective:    s.Objective,
+		Good:         s.Good.CountSince(since),
+		Total:        s.Total.CountSince(since),
+		Availability: 1,
+		Remaining:    1,
+	}
+	if budget.Total == 0 {
+		return budget
+	}
+
+	budget.Availability = float64(budget.Good) / float64(budget.Total)
+	budget.Remaining = 1 - (1-budget.Availability)/(1-s.Objective)
+	return budget
+}
+
+// Clean cleans the events older than a date
+func (s *SLO) Clean(before time.Time) int64 {
+	return s.Good.Clean(before) + s.Total.Clean(before)
+}
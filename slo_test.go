@@ -0,0 +1,144 @@
+package main
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ali.ghanem/http-log-monitoring/commonlog"
+	"github.com/ali.ghanem/http-log-monitoring/metric"
+	"github.com/ali.ghanem/http-log-monitoring/timetest"
+)
+
+// recordEvents records events of the section at a date: the good ones then the server errors
+func recordEvents(slo *SLO, date time.Time, good int, errors int) {
+	for i := 0; i < good+errors; i++ {
+		status := http.StatusOK
+		if i >= good {
+			status = http.StatusInternalServerError
+		}
+		slo.Record(commonlog.Event{Date: date, Status: status, Section: slo.Section}, 1)
+	}
+}
+
+func TestSLO_BurnRate(t *testing.T) {
+	timetest.FreezeTime()
+	defer timetest.UnfreezeTime()
+
+	type testCase struct {
+		Good             int
+		Errors           int
+		ExpectedBurnRate float64
+	}
+
+	cases := map[string]testCase{
+		"no event": {
+			ExpectedBurnRate: 0,
+		},
+		"no error": {
+			Good:             1000,
+			ExpectedBurnRate: 0,
+		},
+		"errors consuming exactly the budget": {
+			Good:             990,
+			Errors:           10,
+			ExpectedBurnRate: 1,
+		},
+		"errors consuming the budget 20 times faster": {
+			Good:             800,
+			Errors:           200,
+			ExpectedBurnRate: 20,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			slo := NewSLO("api", 0.99, metric.SystemClock)
+			recordEvents(slo, time.Now().Add(-2*time.Minute), c.Good, c.Errors)
+			// errors out of the window
+			recordEvents(slo, time.Now().Add(-2*time.Hour), 0, 100)
+
+			actual := slo.BurnRate(time.Now().Add(-1 * time.Hour))
+			if c.ExpectedBurnRate-actual > 1e-9 || actual-c.ExpectedBurnRate > 1e-9 {
+				t.Fatal("unexpected burn rate", "expected", c.ExpectedBurnRate, "actual", actual)
+			}
+		})
+	}
+}
+
+func TestSLO_Budget(t *testing.T) {
+	timetest.FreezeTime()
+	defer timetest.UnfreezeTime()
+
+	slo := NewSLO("api", 0.9, metric.SystemClock)
+	recordEvents(slo, time.Now().Add(-24*time.Hour), 95, 5)
+
+	expected := ErrorBudget{Section: "api", Objective: 0.9, Good: 95, Total: 100, Availability: 0.95, Remaining: 0.5}
+	actual := slo.Budget(time.Now().Add(-1 * DefaultSLOWindow))
+	if expected.Good != actual.Good || expected.Total != actual.Total ||
+		expected.Availability-actual.Availability > 1e-9 || expected.Remaining-actual.Remaining > 1e-9 ||
+		actual.Remaining-expected.Remaining > 1e-9 {
+		t.Fatal("unexpected error budget", "expected", expected, "actual", actual)
+	}
+
+	// the events out of the window are cleaned
+	cleaned := slo.Clean(time.Now().Add(-1 * time.Hour))
+	if cleaned != 2 {
+		t.Fatal("unexpected cleaned buckets", "expected", 2, "actual", cleaned)
+	}
+
+	expected = ErrorBudget{Section: "api", Objective: 0.9, Availability: 1, Remaining: 1}
+	actual = slo.Budget(time.Now().Add(-1 * DefaultSLOWindow))
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatal("unexpected error budget", "expected", expected, "actual", actual)
+	}
+}
+
+func TestLogMonitor_CheckBurnRates(t *testing.T) {
+	timetest.FreezeTime()
+	defer timetest.UnfreezeTime()
+
+	now := time.Now()
+	m := setupLogMonitor(t)
+	m.AddSLO("api", 0.999)
+
+	// 2% of errors during the last 5 minutes: a burn rate of 20 on the short windows
+	for i := 0; i < 1000; i++ {
+		status := http.StatusOK
+		if i%50 == 0 {
+			status = http.StatusBadGateway
+		}
+		m.HandleEvent(commonlog.Event{Date: now.Add(-1 * time.Minute), Status: status, Section: "api"})
+	}
+	// no error before: the fast burn long window is above 14.4 but not the slow burn long window
+	for i := 0; i < 200; i++ {
+		m.HandleEvent(commonlog.Event{Date: now.Add(-50 * time.Minute), Status: http.StatusOK, Section: "api"})
+	}
+	for i := 0; i < 3000; i++ {
+		m.HandleEvent(commonlog.Event{Date: now.Add(-5 * time.Hour), Status: http.StatusOK, Section: "api"})
+	}
+
+	alerts := m.CheckBurnRates()
+	if len(alerts) != 1 || alerts[0].Name != SLOFastBurnAlert || alerts[0].State != AlertFiring ||
+		alerts[0].Key != "api" || alerts[0].Hits != 1200 || alerts[0].ValueThreshold != 14.4 {
+		t.Fatal("unexpected alerts", "expected", SLOFastBurnAlert, "actual", alerts)
+	}
+
+	// the errors stop: the short window resolves the alert
+	m.SLOs["api"] = NewSLO("api", 0.999, metric.SystemClock)
+	recordEvents(m.SLOs["api"], now.Add(-50*time.Minute), 1000, 20)
+	recordEvents(m.SLOs["api"], now.Add(-1*time.Minute), 100, 0)
+	alerts = m.CheckBurnRates()
+	if len(alerts) != 1 || alerts[0].Name != SLOFastBurnAlert || alerts[0].State != AlertResolved {
+		t.Fatal("unexpected alerts", "expected", AlertResolved, "actual", alerts)
+	}
+	if len(m.LastSLOAlerts) != 0 {
+		t.Fatal("unexpected alerts saved", "actual", m.LastSLOAlerts)
+	}
+
+	statistics := m.Statistics(1)
+	if len(statistics.ErrorBudgets) != 1 || statistics.ErrorBudgets[0].Section != "api" {
+		t.Fatal("unexpected error budgets", "actual", statistics.ErrorBudgets)
+	}
+}
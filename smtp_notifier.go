@@ -0,0 +1,372 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"log"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// Default templates of the emails, the data is a smtpMessage
+const (
+	defaultSMTPTextTemplate = `{{range .Alerts}}[{{.Status | upper}}] {{.Name}} on {{.Subject}}
+{{.Description}}
+started at: {{.StartsAt}}
+triggered at: {{.TriggeredAt}}
+
+{{end}}`
+
+	defaultSMTPHTMLTemplate = `<html><body>{{range .Alerts}}
+<h3>[{{.Status | upper}}] {{.Name}} on {{.Subject}}</h3>
+<p>{{.Description}}<br/>started at: {{.StartsAt}}<br/>triggered at: {{.TriggeredAt}}</p>
+{{end}}</body></html>`
+)
+
+// Default values of the smtp notifier
+const (
+	defaultSMTPTimeout   = 10 * time.Second
+	defaultSMTPQueueSize = 100
+)
+
+// SMTPConfig describes how to send the alerts by email,
+// zero values are replaced by the default ones
+type SMTPConfig struct {
+	Address    string              // Address of the smtp server: host:port
+	From       string              // Sender of the emails
+	Recipients map[string][]string // Recipients by rule name, AnyKey for the rules without their own recipients
+	Username   string              // Optional, authenticates with PLAIN auth when set
+	Password   string
+	StartTLS   bool // Requires STARTTLS before sending the emails
+
+	DigestInterval time.Duration // Interval to send the resolved alerts in a single email, 0 to send them immediately
+
+	TextTemplate string // Go template of the plain text part
+	HTMLTemplate string // Go html template of the html part
+
+	Timeout   time.Duration // Timeout of the connection and of the exchange with the smtp server
+	QueueSize int           // Number of alerts waiting to be sent
+}
+
+// redacted replaces the secrets in the logs
+const redacted = "xxxxx"
+
+// String describes the configuration without the password, so it can be logged
+func (c SMTPConfig) String() string {
+	// the alias has no String method, which avoids an infinite recursion
+	type config SMTPConfig
+	if len(c.Password) > 0 {
+		c.Password = redacted
+	}
+	return fmt.Sprintf("%+v", config(c))
+}
+
+// SMTPNotifier sends an email for each alert transition.
+// In digest mode the resolved alerts are batched and sent periodically.
+type SMTPNotifier struct {
+	config       SMTPConfig
+	textTemplate *template.Template
+	htmlTemplate *htmltemplate.Template
+
+	queue chan Alert
+	wg    sync.WaitGroup
+
+	// resolved alerts waiting for the next digest
+	digest []Alert
+}
+
+// smtpMessage is the data available in the email templates
+type smtpMessage struct {
+	Alerts []Alert
+}
+
+func NewSMTPNotifier(config SMTPConfig) (*SMTPNotifier, error) {
+	if len(config.Address) == 0 {
+		return nil, errors.New("smtp address is missing")
+	}
+	if len(config.From) == 0 {
+		return nil, errors.New("smtp sender is missing")
+	}
+	if len(config.Recipients) == 0 {
+		return nil, errors.New("smtp recipients are missing")
+	}
+	if len(config.TextTemplate) == 0 {
+		config.TextTemplate = defaultSMTPTextTemplate
+	}
+	if len(config.HTMLTemplate) == 0 {
+		config.HTMLTemplate = defaultSMTPHTMLTemplate
+	}
+	if config.Timeout == 0 {
+		config.Timeout = defaultSMTPTimeout
+	}
+	if config.QueueSize == 0 {
+		config.QueueSize = defaultSMTPQueueSize
+	}
+
+	funcs := map[string]interface{}{"upper": strings.ToUpper}
+	textTemplate, err := template.New("text").Funcs(funcs).Parse(config.TextTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse smtp text template: %w", err)
+	}
+	htmlTemplate, err := htmltemplate.New("html").Funcs(funcs).Parse(config.HTMLTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse smtp html template: %w", err)
+	}
+
+	n := &SMTPNotifier{
+		config:       config,
+		textTemplate: textTemplate,
+		htmlTemplate: htmlTemplate,
+		queue:        make(chan Alert, config.QueueSize),
+	}
+
+	n.wg.Add(1)
+	go n.run()
+
+	return n, nil
+}
+
+// Notify queues the alert, it returns ErrQueueFull when the alert cannot be queued
+func (n *SMTPNotifier) Notify(alert Alert) error {
+	select {
+	case n.queue <- alert:
+		return nil
+	default:
+		return fmt.Errorf("smtp: %w - alert %s dropped", ErrQueueFull, alert.ID())
+	}
+}
+
+// Close stops the notifier once the queued alerts and the digest are sent
+func (n *SMTPNotifier) Close() error {
+	close(n.queue)
+	n.wg.Wait()
+	return nil
+}
+
+// run sends the queued alerts and the digest periodically
+func (n *SMTPNotifier) run() {
+	defer n.wg.Done()
+
+	// the digest ticker never fires when the digest mode is disabled
+	var digestTicks <-chan time.Time
+	if n.config.DigestInterval > 0 {
+		ticker := time.NewTicker(n.config.DigestInterval)
+		defer ticker.Stop()
+		digestTicks = ticker.C
+	}
+
+	for {
+		select {
+		case alert, ok := <-n.queue:
+			if !ok {
+				n.sendDigest()
+				return
+			}
+
+			if alert.State == AlertResolved && n.config.DigestInterval > 0 {
+				n.digest = append(n.digest, alert)
+				continue
+			}
+
+			n.sendAll([]Alert{alert})
+		case <-digestTicks:
+			n.sendDigest()
+		}
+	}
+}
+
+// sendDigest sends the resolved alerts waiting for the digest
+func (n *SMTPNotifier) sendDigest() {
+	if len(n.digest) == 0 {
+		return
+	}
+
+	n.sendAll(n.digest)
+	n.digest = nil
+}
+
+// sendAll sends the alerts, grouped in an email by recipients
+func (n *SMTPNotifier) sendAll(alerts []Alert) {
+	groups := make(map[string][]Alert)
+	for _, alert := range alerts {
+		to := strings.Join(n.recipients(alert), ",")
+		groups[to] = append(groups[to], alert)
+	}
+
+	for to, group := range groups {
+		err := n.send(strings.Split(to, ","), group)
+		if err != nil {
+			log.Println("cannot send alert email", "to", to, "err", err)
+		}
+	}
+}
+
+// recipients returns the sorted recipients of the alert rule
+func (n *SMTPNotifier) recipients(alert Alert) []string {
+	recipients, ok := n.config.Recipients[alert.Name]
+	if !ok {
+		recipients = n.config.Recipients[AnyKey]
+	}
+
+	sorted := append([]string(nil), recipients...)
+	sort.Strings(sorted)
+	return sorted
+}
+
+// send delivers an email about the alerts to the recipients
+func (n *SMTPNotifier) send(to []string, alerts []Alert) error {
+	if len(to) == 0 || len(to[0]) == 0 {
+		return errors.New("no recipient for the alerts")
+	}
+
+	message, err := n.message(to, alerts)
+	if err != nil {
+		return err
+	}
+
+	host, _, err := net.SplitHostPort(n.config.Address)
+	if err != nil {
+		return fmt.Errorf("invalid smtp address: %w", err)
+	}
+
+	conn, err := net.DialTimeout("tcp", n.config.Address, n.config.Timeout)
+	if err != nil {
+		return err
+	}
+	// a server which stops answering does not block the notifier
+	err = conn.SetDeadline(time.Now().Add(n.config.Timeout))
+	if err != nil {
+		_ = conn.Close()
+		return err
+	}
+
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		_ = conn.Close()
+		return err
+	}
+	defer func() {
+		_ = client.Close()
+	}()
+
+	if n.config.StartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return errors.New("smtp server does not support STARTTLS")
+		}
+		err = client.StartTLS(&tls.Config{ServerName: host})
+		if err != nil {
+			return fmt.Errorf("cannot start tls: %w", err)
+		}
+	}
+
+	if len(n.config.Username) > 0 {
+		err = client.Auth(smtp.PlainAuth("", n.config.Username, n.config.Password, host))
+		if err != nil {
+			return fmt.Errorf("cannot authenticate: %w", err)
+		}
+	}
+
+	err = client.Mail(n.config.From)
+	if err != nil {
+		return err
+	}
+	for _, recipient := range to {
+		err = client.Rcpt(recipient)
+		if err != nil {
+			return err
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(message)
+	if err != nil {
+		return err
+	}
+	err = w.Close()
+	if err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
+
+// message renders the email with a plain text and an html part
+func (n *SMTPNotifier) message(to []string, alerts []Alert) ([]byte, error) {
+	data := smtpMessage{Alerts: alerts}
+
+	var text bytes.Buffer
+	err := n.textTemplate.Execute(&text, data)
+	if err != nil {
+		return nil, fmt.Errorf("cannot render smtp text template: %w", err)
+	}
+
+	var html bytes.Buffer
+	err = n.htmlTemplate.Execute(&html, data)
+	if err != nil {
+		return nil, fmt.Errorf("cannot render smtp html template: %w", err)
+	}
+
+	var body bytes.Buffer
+	parts := multipart.NewWriter(&body)
+	for _, part := range []struct {
+		contentType string
+		content     []byte
+	}{
+		{"text/plain; charset=utf-8", text.Bytes()},
+		{"text/html; charset=utf-8", html.Bytes()},
+	} {
+		w, err := parts.CreatePart(textproto.MIMEHeader{"Content-Type": {part.contentType}})
+		if err != nil {
+			return nil, err
+		}
+		_, err = w.Write(part.content)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = parts.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	var message bytes.Buffer
+	headers := [][2]string{
+		{"From", n.config.From},
+		{"To", strings.Join(to, ", ")},
+		{"Subject", mime.QEncoding.Encode("utf-8", emailSubject(alerts))},
+		{"Date", time.Now().Format(time.RFC1123Z)},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", fmt.Sprintf("multipart/alternative; boundary=%q", parts.Boundary())},
+	}
+	for _, header := range headers {
+		fmt.Fprintf(&message, "%s: %s\r\n", header[0], header[1])
+	}
+	message.WriteString("\r\n")
+	message.Write(body.Bytes())
+
+	return message.Bytes(), nil
+}
+
+// emailSubject summarizes the alerts of an email
+func emailSubject(alerts []Alert) string {
+	if len(alerts) == 1 {
+		alert := alerts[0]
+		return fmt.Sprintf("[%s] %s on %s", strings.ToUpper(alert.Status()), alert.Name, alert.Subject())
+	}
+
+	return fmt.Sprintf("[%s] %v alerts", strings.ToUpper(alerts[0].Status()), len(alerts))
+}
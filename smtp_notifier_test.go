@@ -0,0 +1,359 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeSMTPServer is a minimal smtp server recording the emails received
+type fakeSMTPServer struct {
+	listener net.Listener
+	auth     bool // advertises the PLAIN authentication
+
+	lock   sync.Mutex
+	emails []fakeEmail
+	wg     sync.WaitGroup
+}
+
+type fakeEmail struct {
+	From          string
+	To            []string
+	Data          []byte
+	Authenticated bool
+}
+
+func newFakeSMTPServer(t *testing.T, auth bool) *fakeSMTPServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &fakeSMTPServer{listener: listener, auth: auth}
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			s.wg.Add(1)
+			go s.serve(conn)
+		}
+	}()
+
+	return s
+}
+
+func (s *fakeSMTPServer) serve(conn net.Conn) {
+	defer s.wg.Done()
+	defer conn.Close()
+
+	tp := textproto.NewConn(conn)
+	_ = tp.PrintfLine("220 localhost ESMTP fake")
+
+	var email fakeEmail
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+
+		command := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch command {
+		case "EHLO":
+			_ = tp.PrintfLine("250-localhost")
+			if s.auth {
+				_ = tp.PrintfLine("250-AUTH PLAIN")
+			}
+			_ = tp.PrintfLine("250 8BITMIME")
+		case "AUTH":
+			email.Authenticated = true
+			_ = tp.PrintfLine("235 authenticated")
+		case "MAIL":
+			email.From = smtpPath(line)
+			_ = tp.PrintfLine("250 ok")
+		case "RCPT":
+			email.To = append(email.To, smtpPath(line))
+			_ = tp.PrintfLine("250 ok")
+		case "DATA":
+			_ = tp.PrintfLine("354 go ahead")
+			email.Data, err = tp.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			s.lock.Lock()
+			s.emails = append(s.emails, email)
+			s.lock.Unlock()
+			email = fakeEmail{}
+			_ = tp.PrintfLine("250 ok")
+		case "QUIT":
+			_ = tp.PrintfLine("221 bye")
+			return
+		default:
+			_ = tp.PrintfLine("502 not implemented")
+		}
+	}
+}
+
+// smtpPath extracts the address of a MAIL or RCPT command
+func smtpPath(line string) string {
+	start := strings.Index(line, "<")
+	end := strings.Index(line, ">")
+	if start < 0 || end < start {
+		return ""
+	}
+	return line[start+1 : end]
+}
+
+func (s *fakeSMTPServer) close() []fakeEmail {
+	_ = s.listener.Close()
+	s.wg.Wait()
+
+	return s.emails
+}
+
+// readEmail returns the subject and the text and html parts of an email
+func readEmail(t *testing.T, data []byte) (string, map[string]string) {
+	message, err := mail.ReadMessage(strings.NewReader(string(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(message.Header.Get("Subject"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, params, err := mime.ParseMediaType(message.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parts := make(map[string]string)
+	reader := multipart.NewReader(message.Body, params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err != nil {
+			break
+		}
+		content, err := ioutil.ReadAll(part)
+		if err != nil {
+			t.Fatal(err)
+		}
+		contentType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		parts[contentType] = string(content)
+	}
+
+	return subject, parts
+}
+
+func TestSMTPNotifier_Notify(t *testing.T) {
+	triggeredAt := time.Date(2006, 01, 02, 15, 04, 05, 000, time.UTC)
+	firing := Alert{Name: HighTrafficAlert, Scope: SectionScope, Key: "login", State: AlertFiring, Hits: 6000, AverageRate: 100, Threshold: 50, TriggeredAt: triggeredAt}
+	noData := Alert{Name: NoDataAlert, State: AlertFiring, Threshold: 30, TriggeredAt: triggeredAt}
+
+	server := newFakeSMTPServer(t, true)
+
+	n, err := NewSMTPNotifier(SMTPConfig{
+		Address:  server.listener.Addr().String(),
+		From:     "monitor@example.com",
+		Username: "monitor",
+		Password: "secret",
+		Recipients: map[string][]string{
+			NoDataAlert: {"ops@example.com"},
+			AnyKey:      {"dev2@example.com", "dev1@example.com"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, alert := range []Alert{firing, noData} {
+		err = n.Notify(alert)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err = n.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	emails := server.close()
+	if len(emails) != 2 {
+		t.Fatal("unexpected emails", "expected", 2, "actual", len(emails))
+	}
+
+	expectedTo := [][]string{{"dev1@example.com", "dev2@example.com"}, {"ops@example.com"}}
+	expectedSubjects := []string{"[FIRING] high_traffic on section /login", "[FIRING] no_data on traffic"}
+	for i, email := range emails {
+		if !email.Authenticated || email.From != "monitor@example.com" {
+			t.Fatal("unexpected sender", "from", email.From, "authenticated", email.Authenticated)
+		}
+
+		if !reflect.DeepEqual(expectedTo[i], email.To) {
+			t.Fatal("unexpected recipients", "expected", expectedTo[i], "actual", email.To)
+		}
+
+		subject, parts := readEmail(t, email.Data)
+		if expectedSubjects[i] != subject {
+			t.Fatal("unexpected subject", "expected", expectedSubjects[i], "actual", subject)
+		}
+
+		if !strings.Contains(parts["text/plain"], "[FIRING]") || !strings.Contains(parts["text/html"], "<h3>[FIRING]") {
+			t.Fatal("unexpected content", "actual", parts)
+		}
+	}
+}
+
+func TestSMTPNotifier_Digest(t *testing.T) {
+	triggeredAt := time.Date(2006, 01, 02, 15, 04, 05, 000, time.UTC)
+	alerts := []Alert{
+		{Name: HighTrafficAlert, State: AlertFiring, Hits: 12000, AverageRate: 200, Threshold: 100, TriggeredAt: triggeredAt},
+		{Name: HighTrafficAlert, State: AlertResolved, Hits: 600, AverageRate: 10, Threshold: 100, TriggeredAt: triggeredAt},
+		{Name: HighTrafficAlert, Scope: HostScope, Key: "10.1.2.3", State: AlertResolved, Threshold: 100, TriggeredAt: triggeredAt},
+	}
+
+	server := newFakeSMTPServer(t, false)
+
+	n, err := NewSMTPNotifier(SMTPConfig{
+		Address:        server.listener.Addr().String(),
+		From:           "monitor@example.com",
+		Recipients:     map[string][]string{AnyKey: {"dev@example.com"}},
+		DigestInterval: time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, alert := range alerts {
+		err = n.Notify(alert)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the digest is sent when the notifier is closed
+	err = n.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	emails := server.close()
+	if len(emails) != 2 {
+		t.Fatal("unexpected emails", "expected", 2, "actual", len(emails))
+	}
+
+	subject, _ := readEmail(t, emails[0].Data)
+	if subject != "[FIRING] high_traffic on traffic" {
+		t.Fatal("unexpected firing subject", "actual", subject)
+	}
+
+	subject, parts := readEmail(t, emails[1].Data)
+	if subject != "[RESOLVED] 2 alerts" {
+		t.Fatal("unexpected digest subject", "actual", subject)
+	}
+	if strings.Count(parts["text/plain"], "[RESOLVED]") != 2 {
+		t.Fatal("unexpected digest content", "actual", parts["text/plain"])
+	}
+}
+
+func TestSMTPNotifier_StartTLSNotSupported(t *testing.T) {
+	server := newFakeSMTPServer(t, false)
+
+	n, err := NewSMTPNotifier(SMTPConfig{
+		Address:    server.listener.Addr().String(),
+		From:       "monitor@example.com",
+		Recipients: map[string][]string{AnyKey: {"dev@example.com"}},
+		StartTLS:   true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = n.Notify(Alert{Name: HighTrafficAlert, State: AlertFiring})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = n.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	emails := server.close()
+	if len(emails) != 0 {
+		t.Fatal("email sent without tls", "actual", len(emails))
+	}
+}
+
+func TestSMTPNotifier_ServerNotAnswering(t *testing.T) {
+	// the connection is accepted but the server never greets the client
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	n, err := NewSMTPNotifier(SMTPConfig{
+		Address:    listener.Addr().String(),
+		From:       "monitor@example.com",
+		Recipients: map[string][]string{AnyKey: {"dev@example.com"}},
+		Timeout:    100 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = n.Notify(Alert{Name: HighTrafficAlert, State: AlertFiring})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	closed := make(chan error)
+	go func() {
+		closed <- n.Close()
+	}()
+	select {
+	case err := <-closed:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("notifier blocked by the smtp server")
+	}
+}
+
+func TestSMTPConfig_String(t *testing.T) {
+	config := Configuration{SMTP: SMTPConfig{Address: "smtp.example.com:587", Username: "monitor", Password: "s3cr3t"}}
+
+	for _, description := range []string{fmt.Sprint(config), fmt.Sprintf("%+v", config), config.SMTP.String()} {
+		if strings.Contains(description, "s3cr3t") {
+			t.Fatal("unexpected password in the configuration", "actual", description)
+		}
+		if !strings.Contains(description, "smtp.example.com:587") {
+			t.Fatal("unexpected configuration", "expected", "smtp.example.com:587", "actual", description)
+		}
+	}
+}
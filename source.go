@@ -0,0 +1,197 @@
+package main
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ali.ghanem/http-log-monitoring/commonlog"
+)
+
+// DefaultDiscoveryInterval is the interval to evaluate again the glob patterns of the logs
+const DefaultDiscoveryInterval = 10 * time.Second
+
+// LogLine is a line read from a log source
+type LogLine struct {
+	Source   string // Label of the source of the line, the path of the file for the tailed logs
+	Text     string
+	Err      error
+	Position FilePosition      // Position of the end of the line for the tailed logs
+	Labels   map[string]string // Labels of the transport of the line, such as the syslog hostname
+	Event    *commonlog.Event  // Event already parsed by the source, the text is not parsed then
+}
+
+// FileSource tails the files matching a list of paths and glob patterns.
+// The patterns are evaluated again periodically to tail the new files,
+// each file has its own follower and all the lines are sent to the same channel.
+// The named pipes are read as they are written and are never at their end.
+type FileSource struct {
+	patterns          []string
+	discoveryInterval time.Duration
+	pollInterval      time.Duration
+	checkpoints       *Checkpoints // optional, positions to resume the files
+
+	lines chan LogLine
+	stop  chan struct{}
+	wg    sync.WaitGroup
+
+	mutex sync.Mutex
+	files map[string]bool // files followed
+}
+
+// NewFileSource creates a source of the files matching the patterns, 0 applies the default discovery interval.
+// The files are read from the positions of the checkpoints when they are set, from their start otherwise.
+func NewFileSource(patterns []string, discoveryInterval time.Duration, checkpoints *Checkpoints) *FileSource {
+	if discoveryInterval == 0 {
+		discoveryInterval = DefaultDiscoveryInterval
+	}
+
+	return &FileSource{
+		patterns:          patterns,
+		discoveryInterval: discoveryInterval,
+		pollInterval:      DefaultPollInterval,
+		checkpoints:       checkpoints,
+		lines:             make(chan LogLine),
+		stop:              make(chan struct{}),
+		files:             make(map[string]bool),
+	}
+}
+
+// Start tails the files matching the patterns and looks periodically for new files
+func (s *FileSource) Start() error {
+	err := s.discover()
+	if err != nil {
+		return err
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ticker := time.NewTicker(s.discoveryInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+				err := s.discover()
+				if err != nil {
+					log.Println("cannot discover log files", "err", err)
+				}
+			}
+		}
+	}()
+
+	return nil
+}
+
+// Lines returns the lines read from all the files, the channel is never closed
+func (s *FileSource) Lines() <-chan LogLine {
+	return s.lines
+}
+
+// Files returns the paths of the files tailed
+func (s *FileSource) Files() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	files := make([]string, 0, len(s.files))
+	for file := range s.files {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	return files
+}
+
+// Stop stops the followers, the lines not received yet are read again from the checkpoints on restart
+func (s *FileSource) Stop() {
+	s.mutex.Lock()
+	close(s.stop)
+	s.mutex.Unlock()
+
+	s.wg.Wait()
+}
+
+// discover tails the files matching the patterns which are not tailed yet
+func (s *FileSource) discover() error {
+	files, err := matchFiles(s.patterns)
+	if err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	select {
+	case <-s.stop:
+		// the followers are stopped
+		return nil
+	default:
+	}
+
+	for _, file := range files {
+		if s.files[file] {
+			continue
+		}
+
+		s.files[file] = true
+		follower := &fileFollower{path: file, pollInterval: s.pollInterval, lines: s.lines, stop: s.stop}
+
+		info, err := os.Stat(file)
+		if err == nil && info.Mode()&os.ModeNamedPipe != 0 {
+			log.Println("reading named pipe", "file", file)
+			s.wg.Add(1)
+			go func() {
+				defer s.wg.Done()
+				follower.followPipe()
+			}()
+			continue
+		}
+
+		var start FilePosition
+		resume := false
+		if s.checkpoints != nil {
+			start, resume = s.checkpoints.Position(file)
+		}
+		log.Println("tailing log file", "file", file, "offset", start.Offset)
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			follower.follow(start, resume)
+		}()
+	}
+
+	return nil
+}
+
+// matchFiles returns the files matching the paths and glob patterns.
+// The paths without glob meta characters are always returned so they are tailed even before they exist.
+func matchFiles(patterns []string) ([]string, error) {
+	var files []string
+	seen := make(map[string]bool)
+	for _, pattern := range patterns {
+		matches := []string{pattern}
+		if strings.ContainsAny(pattern, "*?[") {
+			var err error
+			matches, err = filepath.Glob(pattern)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		for _, file := range matches {
+			if !seen[file] {
+				seen[file] = true
+				files = append(files, file)
+			}
+		}
+	}
+
+	return files, nil
+}
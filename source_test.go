@@ -0,0 +1,96 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// appendLine writes a line at the end of a file
+func appendLine(t *testing.T, path string, line string) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	_, err = file.WriteString(line + "\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// readLine waits for a line of the source
+func readLine(t *testing.T, source LineSource) LogLine {
+	select {
+	case line := <-source.Lines():
+		return line
+	case <-time.After(5 * time.Second):
+		t.Fatal("no line received")
+		return LogLine{}
+	}
+}
+
+func TestFileSource_Discover(t *testing.T) {
+	dir, err := ioutil.TempDir("", "source")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	first := filepath.Join(dir, "first.access.log")
+	appendLine(t, first, "first line")
+	// not matching the pattern
+	appendLine(t, filepath.Join(dir, "first.error.log"), "error line")
+
+	source := NewFileSource([]string{filepath.Join(dir, "*.access.log")}, 100*time.Millisecond, nil)
+	err = source.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer source.Stop()
+
+	line := readLine(t, source)
+	if line.Source != first || line.Text != "first line" {
+		t.Fatal("unexpected line", "expected", first, "actual", line)
+	}
+
+	// a new file matching the pattern is tailed
+	second := filepath.Join(dir, "second.access.log")
+	appendLine(t, second, "second line")
+
+	line = readLine(t, source)
+	if line.Source != second || line.Text != "second line" {
+		t.Fatal("unexpected line", "expected", second, "actual", line)
+	}
+
+	expected := []string{first, second}
+	if !reflect.DeepEqual(expected, source.Files()) {
+		t.Fatal("unexpected files", "expected", expected, "actual", source.Files())
+	}
+}
+
+func TestMatchFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "source")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	appendLine(t, filepath.Join(dir, "a.log"), "")
+	appendLine(t, filepath.Join(dir, "b.log"), "")
+
+	// the paths without pattern are kept even when they do not exist, the duplicates are removed
+	files, err := matchFiles([]string{filepath.Join(dir, "*.log"), filepath.Join(dir, "a.log"), filepath.Join(dir, "c.log")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{filepath.Join(dir, "a.log"), filepath.Join(dir, "b.log"), filepath.Join(dir, "c.log")}
+	if !reflect.DeepEqual(expected, files) {
+		t.Fatal("unexpected files", "expected", expected, "actual", files)
+	}
+}
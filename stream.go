@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+)
+
+// StdinPath is the log path of the standard input
+const StdinPath = "-"
+
+// LineSource sends the lines read from the logs
+type LineSource interface {
+	Start() error
+	// Lines returns the lines read, the channel is closed when the source has no more lines to read
+	Lines() <-chan LogLine
+	Stop()
+}
+
+// MultiSource merges the lines of several sources, its channel is closed when all their channels are closed
+type MultiSource struct {
+	sources []LineSource
+
+	lines chan LogLine
+	stop  chan struct{}
+	wg    sync.WaitGroup
+}
+
+func NewMultiSource(sources ...LineSource) *MultiSource {
+	return &MultiSource{
+		sources: sources,
+		lines:   make(chan LogLine),
+		stop:    make(chan struct{}),
+	}
+}
+
+// Start starts the sources and forwards their lines
+func (m *MultiSource) Start() error {
+	for i, source := range m.sources {
+		err := source.Start()
+		if err != nil {
+			for _, started := range m.sources[:i] {
+				started.Stop()
+			}
+			return err
+		}
+	}
+
+	for _, source := range m.sources {
+		m.wg.Add(1)
+		go func(source LineSource) {
+			defer m.wg.Done()
+			for {
+				var line LogLine
+				var ok bool
+				select {
+				case line, ok = <-source.Lines():
+					if !ok {
+						return
+					}
+				case <-m.stop:
+					return
+				}
+
+				select {
+				case m.lines <- line:
+				case <-m.stop:
+					return
+				}
+			}
+		}(source)
+	}
+
+	go func() {
+		m.wg.Wait()
+		close(m.lines)
+	}()
+
+	return nil
+}
+
+// Lines returns the lines read from all the sources
+func (m *MultiSource) Lines() <-chan LogLine {
+	return m.lines
+}
+
+// Stop stops the sources
+func (m *MultiSource) Stop() {
+	close(m.stop)
+	for _, source := range m.sources {
+		source.Stop()
+	}
+	m.wg.Wait()
+}
+
+// StreamSource reads the lines of a stream such as the standard input until its end.
+// It is used to pipe the logs of another command into the monitor.
+type StreamSource struct {
+	name   string
+	reader io.Reader
+
+	lines chan LogLine
+	stop  chan struct{}
+	once  sync.Once
+}
+
+// NewStreamSource creates a source of the lines of the reader, labelled with the name
+func NewStreamSource(name string, reader io.Reader) *StreamSource {
+	return &StreamSource{
+		name:   name,
+		reader: reader,
+		lines:  make(chan LogLine),
+		stop:   make(chan struct{}),
+	}
+}
+
+// NewStdinSource creates a source of the lines of the standard input
+func NewStdinSource() *StreamSource {
+	return NewStreamSource(StdinPath, os.Stdin)
+}
+
+// Start reads the stream, the lines channel is closed at its end
+func (s *StreamSource) Start() error {
+	go func() {
+		readStream(s.name, s.reader, s.lines, s.stop)
+		close(s.lines)
+	}()
+
+	return nil
+}
+
+// Lines returns the lines read from the stream
+func (s *StreamSource) Lines() <-chan LogLine {
+	return s.lines
+}
+
+// Stop stops sending the lines, a read in progress is not interrupted
+func (s *StreamSource) Stop() {
+	s.once.Do(func() {
+		close(s.stop)
+	})
+}
+
+// readStream sends the lines of the reader until its end, it returns false when it is stopped
+func readStream(name string, reader io.Reader, lines chan<- LogLine, stop <-chan struct{}) bool {
+	send := func(line LogLine) bool {
+		select {
+		case lines <- line:
+			return true
+		case <-stop:
+			return false
+		}
+	}
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	for scanner.Scan() {
+		if !send(LogLine{Source: name, Text: scanner.Text()}) {
+			return false
+		}
+	}
+
+	err := scanner.Err()
+	if err != nil {
+		return send(LogLine{Source: name, Err: fmt.Errorf("cannot read %s: %w", name, err)})
+	}
+	return true
+}
+
+// followPipe reads the lines written in the named pipe until the follower is stopped.
+// The pipe is also opened for writing so its end is never reached when its writers close it,
+// the lines of the next writers are read as they come.
+func (f *fileFollower) followPipe() {
+	pipe, err := os.OpenFile(f.path, os.O_RDWR, 0)
+	if err != nil {
+		f.send(LogLine{Source: f.path, Err: err})
+		return
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-f.stop:
+			// interrupts the pending read
+			pipe.Close()
+		case <-done:
+			pipe.Close()
+		}
+	}()
+
+	if readStream(f.path, pipe, f.lines, f.stop) {
+		log.Println("named pipe closed", "file", f.path)
+	}
+}
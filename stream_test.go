@@ -0,0 +1,33 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStreamSource_Lines(t *testing.T) {
+	source := NewStreamSource(StdinPath, strings.NewReader("first line\nsecond line"))
+	err := source.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer source.Stop()
+
+	for _, expected := range []string{"first line", "second line"} {
+		line := readLine(t, source)
+		if line.Source != StdinPath || line.Text != expected {
+			t.Fatal("unexpected line", "expected", expected, "actual", line)
+		}
+	}
+
+	// the channel is closed at the end of the stream
+	select {
+	case line, ok := <-source.Lines():
+		if ok {
+			t.Fatal("unexpected line", "expected", "end of the stream", "actual", line)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("end of the stream not received")
+	}
+}
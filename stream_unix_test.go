@@ -0,0 +1,54 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestFileSource_NamedPipe(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pipe")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "access.pipe")
+	err = syscall.Mkfifo(path, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	source := NewFileSource([]string{path}, time.Minute, nil)
+	err = source.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the lines of the successive writers are read
+	for _, expected := range []string{"first writer", "second writer"} {
+		appendLine(t, path, expected)
+
+		line := readLine(t, source)
+		if line.Source != path || line.Text != expected || line.Err != nil {
+			t.Fatal("unexpected line", "expected", expected, "actual", line)
+		}
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		source.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("source not stopped while waiting on the pipe")
+	}
+}
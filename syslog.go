@@ -0,0 +1,428 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Labels of the events received by syslog
+const (
+	HostnameLabel = "hostname"
+	AppNameLabel  = "app_name"
+)
+
+// maxDatagramSize is the size of the largest syslog message received over udp
+const maxDatagramSize = 64 * 1024
+
+// rfc3164Timestamp is the timestamp of the BSD syslog messages, the day is padded with a space
+const rfc3164Timestamp = "Jan _2 15:04:05"
+
+var errInvalidSyslog = errors.New("invalid syslog message")
+
+// SyslogMessage is a syslog message without its header
+type SyslogMessage struct {
+	Hostname string
+	AppName  string
+	Message  string
+}
+
+// SyslogSource receives syslog messages over udp, tcp and unix sockets.
+// The addresses are formatted as "udp://:514", "tcp://:601", "unix:///run/monitor.sock" or "unixgram:///run/monitor.sock".
+// The stream connections accept the octet counted and the new line framing (RFC 6587).
+type SyslogSource struct {
+	addresses []string
+
+	lines chan LogLine
+	stop  chan struct{}
+	wg    sync.WaitGroup
+
+	mutex   sync.Mutex
+	closers map[io.Closer]bool // listeners, packet connections and stream connections open
+	sockets []string           // files of the unixgram sockets, removed once stopped
+	stopped bool
+}
+
+func NewSyslogSource(addresses []string) *SyslogSource {
+	return &SyslogSource{
+		addresses: addresses,
+		lines:     make(chan LogLine),
+		stop:      make(chan struct{}),
+		closers:   make(map[io.Closer]bool),
+	}
+}
+
+// Start listens on the addresses
+func (s *SyslogSource) Start() error {
+	for _, address := range s.addresses {
+		network, path, err := splitAddress(address)
+		if err != nil {
+			return err
+		}
+
+		switch network {
+		case "udp", "unixgram":
+			conn, err := net.ListenPacket(network, path)
+			if err != nil {
+				s.Stop()
+				return err
+			}
+			s.track(conn)
+			// unlike the unix listeners, the unixgram connections do not remove their file when closed
+			if network == "unixgram" {
+				s.mutex.Lock()
+				s.sockets = append(s.sockets, path)
+				s.mutex.Unlock()
+			}
+			log.Println("receiving syslog", "address", address)
+
+			s.wg.Add(1)
+			go func(address string) {
+				defer s.wg.Done()
+				s.receive(address, conn)
+			}(address)
+		default:
+			listener, err := net.Listen(network, path)
+			if err != nil {
+				s.Stop()
+				return err
+			}
+			s.track(listener)
+			log.Println("receiving syslog", "address", address)
+
+			s.wg.Add(1)
+			go func(address string) {
+				defer s.wg.Done()
+				s.accept(address, listener)
+			}(address)
+		}
+	}
+
+	return nil
+}
+
+// Lines returns the lines of the messages received, the channel is never closed
+func (s *SyslogSource) Lines() <-chan LogLine {
+	return s.lines
+}
+
+// Stop closes the listeners and the connections, and removes the files of the unixgram sockets
+func (s *SyslogSource) Stop() {
+	s.mutex.Lock()
+	if !s.stopped {
+		s.stopped = true
+		close(s.stop)
+		for closer := range s.closers {
+			closer.Close()
+		}
+		for _, socket := range s.sockets {
+			err := os.Remove(socket)
+			if err != nil && !os.IsNotExist(err) {
+				log.Println("cannot remove syslog socket", "file", socket, "err", err)
+			}
+		}
+	}
+	s.mutex.Unlock()
+
+	s.wg.Wait()
+}
+
+// track closes the listener or the connection when the source is stopped, it returns false if it is already stopped
+func (s *SyslogSource) track(closer io.Closer) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.stopped {
+		closer.Close()
+		return false
+	}
+	s.closers[closer] = true
+	return true
+}
+
+// untrack closes the connection which is no longer read
+func (s *SyslogSource) untrack(closer io.Closer) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.closers, closer)
+	closer.Close()
+}
+
+// receive reads a message in each datagram
+func (s *SyslogSource) receive(address string, conn net.PacketConn) {
+	buffer := make([]byte, maxDatagramSize)
+	for {
+		n, _, err := conn.ReadFrom(buffer)
+		if err != nil {
+			select {
+			case <-s.stop:
+			default:
+				log.Println("cannot receive syslog message", "address", address, "err", err)
+			}
+			return
+		}
+
+		if !s.send(address, buffer[:n]) {
+			return
+		}
+	}
+}
+
+// accept reads the messages of each connection
+func (s *SyslogSource) accept(address string, listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			select {
+			case <-s.stop:
+			default:
+				log.Println("cannot accept syslog connection", "address", address, "err", err)
+			}
+			return
+		}
+		if !s.track(conn) {
+			return
+		}
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer s.untrack(conn)
+
+			scanner := scanFrames(conn)
+			for scanner.Scan() {
+				if message := scanner.Bytes(); len(message) > 0 && !s.send(address, message) {
+					return
+				}
+			}
+			if err := scanner.Err(); err != nil {
+				select {
+				case <-s.stop:
+				default:
+					log.Println("cannot read syslog connection", "address", address, "err", err)
+				}
+			}
+		}()
+	}
+}
+
+// send parses the message and sends its body with the hostname and the app name as labels
+func (s *SyslogSource) send(address string, data []byte) bool {
+	line := LogLine{Source: address}
+	message, err := ParseSyslog(data)
+	if err != nil {
+		line.Err = err
+		line.Text = string(data)
+	} else {
+		line.Text = message.Message
+		line.Labels = map[string]string{HostnameLabel: message.Hostname, AppNameLabel: message.AppName}
+	}
+
+	select {
+	case s.lines <- line:
+		return true
+	case <-s.stop:
+		return false
+	}
+}
+
+// splitAddress returns the network and the address of an url such as "udp://:514"
+func splitAddress(address string) (string, string, error) {
+	parts := strings.SplitN(address, "://", 2)
+	if len(parts) != 2 {
+		return "", "", fmt.Errorf("invalid syslog address %s - expected network://address", address)
+	}
+
+	switch parts[0] {
+	case "udp", "tcp", "unix", "unixgram":
+		return parts[0], parts[1], nil
+	default:
+		return "", "", fmt.Errorf("invalid syslog address %s - unknown network %s", address, parts[0])
+	}
+}
+
+// maxFrameHeaderSize is the size of the longest length prefix of an octet counted frame, with its space
+const maxFrameHeaderSize = 8
+
+// scanFrames returns a scanner of the messages of a stream, they are limited to maxLineSize
+func scanFrames(stream io.Reader) *bufio.Scanner {
+	scanner := bufio.NewScanner(stream)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize+maxFrameHeaderSize)
+	scanner.Split(splitFrame)
+	return scanner
+}
+
+// splitFrame splits a stream into its messages, framed by their length ("LEN SP MSG") when they start
+// with a digit, by a new line otherwise
+func splitFrame(data []byte, atEOF bool) (int, []byte, error) {
+	if len(data) == 0 {
+		return 0, nil, nil
+	}
+
+	if data[0] < '0' || data[0] > '9' {
+		if i := bytes.IndexByte(data, '\n'); i >= 0 {
+			return i + 1, bytes.TrimRight(data[:i], "\r"), nil
+		}
+		if atEOF {
+			return len(data), bytes.TrimRight(data, "\r"), nil
+		}
+		return 0, nil, nil
+	}
+
+	space := bytes.IndexByte(data, ' ')
+	if space < 0 {
+		switch {
+		case len(data) >= maxFrameHeaderSize:
+			return 0, nil, fmt.Errorf("invalid syslog frame length %q", data[:maxFrameHeaderSize])
+		case atEOF:
+			return 0, nil, io.ErrUnexpectedEOF
+		}
+		return 0, nil, nil
+	}
+	length, err := strconv.Atoi(string(data[:space]))
+	if err != nil || length > maxLineSize {
+		return 0, nil, fmt.Errorf("invalid syslog frame length %q", data[:space+1])
+	}
+
+	end := space + 1 + length
+	if len(data) < end {
+		if atEOF {
+			return 0, nil, io.ErrUnexpectedEOF
+		}
+		return 0, nil, nil
+	}
+	return end, bytes.TrimRight(data[space+1:end], "\r\n"), nil
+}
+
+// ParseSyslog strips the header of a RFC 5424 or RFC 3164 syslog message
+func ParseSyslog(data []byte) (SyslogMessage, error) {
+	text := strings.TrimRight(string(data), "\r\n\x00")
+
+	// priority
+	if !strings.HasPrefix(text, "<") {
+		return SyslogMessage{}, fmt.Errorf("%w: no priority", errInvalidSyslog)
+	}
+	end := strings.IndexByte(text, '>')
+	if end < 2 || end > 4 {
+		return SyslogMessage{}, fmt.Errorf("%w: invalid priority", errInvalidSyslog)
+	}
+	priority, err := strconv.Atoi(text[1:end])
+	if err != nil || priority > 191 {
+		return SyslogMessage{}, fmt.Errorf("%w: invalid priority", errInvalidSyslog)
+	}
+	text = text[end+1:]
+
+	if strings.HasPrefix(text, "1 ") {
+		return parseRFC5424(text[2:])
+	}
+	return parseRFC3164(text), nil
+}
+
+// parseRFC5424 parses "TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA [MSG]"
+func parseRFC5424(text string) (SyslogMessage, error) {
+	fields := make([]string, 0, 5)
+	for len(fields) < 5 {
+		end := strings.IndexByte(text, ' ')
+		if end < 0 {
+			return SyslogMessage{}, fmt.Errorf("%w: incomplete header", errInvalidSyslog)
+		}
+		fields = append(fields, nilValue(text[:end]))
+		text = text[end+1:]
+	}
+
+	// structured data
+	if strings.HasPrefix(text, "-") {
+		text = text[1:]
+	} else {
+		for strings.HasPrefix(text, "[") {
+			end := structuredDataEnd(text)
+			if end < 0 {
+				return SyslogMessage{}, fmt.Errorf("%w: unterminated structured data", errInvalidSyslog)
+			}
+			text = text[end+1:]
+		}
+	}
+	text = strings.TrimPrefix(text, " ")
+	text = strings.TrimPrefix(text, "\xef\xbb\xbf")
+
+	return SyslogMessage{Hostname: fields[1], AppName: fields[2], Message: text}, nil
+}
+
+// structuredDataEnd returns the index of the end of the structured data element starting the text
+func structuredDataEnd(text string) int {
+	quoted := false
+	for i := 1; i < len(text); i++ {
+		switch {
+		case text[i] == '\\' && quoted:
+			i++
+		case text[i] == '"':
+			quoted = !quoted
+		case text[i] == ']' && !quoted:
+			return i
+		}
+	}
+	return -1
+}
+
+// nilValue returns the value of a header field, empty when it is the nil value "-"
+func nilValue(field string) string {
+	if field == "-" {
+		return ""
+	}
+	return field
+}
+
+// parseRFC3164 parses "TIMESTAMP HOSTNAME TAG: MSG", the timestamp and the hostname may be omitted.
+// Without timestamp, the first word is the hostname only when a tag follows it,
+// otherwise it belongs to the message like the remote host of an access log line.
+func parseRFC3164(text string) SyslogMessage {
+	var timestamped bool
+	if len(text) > len(rfc3164Timestamp) {
+		_, err := time.Parse(rfc3164Timestamp, text[:len(rfc3164Timestamp)])
+		if err == nil && text[len(rfc3164Timestamp)] == ' ' {
+			text = text[len(rfc3164Timestamp)+1:]
+			timestamped = true
+		}
+	}
+
+	var message SyslogMessage
+	end := strings.IndexByte(text, ' ')
+	if end > 0 && !isTag(text[:end]) && (timestamped || startsWithTag(text[end+1:])) {
+		message.Hostname = text[:end]
+		text = text[end+1:]
+	}
+
+	end = strings.IndexByte(text, ' ')
+	if end > 0 && isTag(text[:end]) {
+		tag := strings.TrimSuffix(text[:end], ":")
+		if bracket := strings.IndexByte(tag, '['); bracket >= 0 {
+			tag = tag[:bracket]
+		}
+		message.AppName = tag
+		text = text[end+1:]
+	}
+
+	message.Message = text
+	return message
+}
+
+// startsWithTag tells if the first word of the text is a tag
+func startsWithTag(text string) bool {
+	end := strings.IndexByte(text, ' ')
+	return end > 0 && isTag(text[:end])
+}
+
+// isTag tells if the field is the tag of a BSD syslog message, such as "nginx:" or "nginx[42]:"
+func isTag(field string) bool {
+	return strings.HasSuffix(field, ":") && len(field) > 1
+}
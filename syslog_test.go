@@ -0,0 +1,189 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const syslogAccessLine = `127.0.0.1 - james [09/May/2018:16:00:39 +0000] "GET /report HTTP/1.0" 200 123`
+
+func TestParseSyslog(t *testing.T) {
+	type testCase struct {
+		Message       string
+		Expected      SyslogMessage
+		ExpectedError error
+	}
+
+	cases := map[string]testCase{
+		"rfc 5424": {
+			Message:  "<190>1 2018-05-09T16:00:39.000Z web-1 nginx 42 access - " + syslogAccessLine,
+			Expected: SyslogMessage{Hostname: "web-1", AppName: "nginx", Message: syslogAccessLine},
+		},
+		"rfc 5424 with structured data": {
+			Message:  `<190>1 2018-05-09T16:00:39Z web-1 nginx - - [meta x="a \] b"][origin ip="10.0.0.1"] ` + syslogAccessLine,
+			Expected: SyslogMessage{Hostname: "web-1", AppName: "nginx", Message: syslogAccessLine},
+		},
+		"rfc 5424 with nil values and bom": {
+			Message:  "<190>1 - - - - - - \xef\xbb\xbf" + syslogAccessLine + "\n",
+			Expected: SyslogMessage{Message: syslogAccessLine},
+		},
+		"rfc 3164": {
+			Message:  "<190>May  9 16:00:39 web-1 nginx: " + syslogAccessLine,
+			Expected: SyslogMessage{Hostname: "web-1", AppName: "nginx", Message: syslogAccessLine},
+		},
+		"rfc 3164 with pid": {
+			Message:  "<190>May 19 16:00:39 web-1 nginx[42]: " + syslogAccessLine,
+			Expected: SyslogMessage{Hostname: "web-1", AppName: "nginx", Message: syslogAccessLine},
+		},
+		"rfc 3164 without hostname": {
+			Message:  "<190>May  9 16:00:39 nginx: " + syslogAccessLine,
+			Expected: SyslogMessage{AppName: "nginx", Message: syslogAccessLine},
+		},
+		"rfc 3164 without timestamp": {
+			Message:  "<190>web-1 nginx: " + syslogAccessLine,
+			Expected: SyslogMessage{Hostname: "web-1", AppName: "nginx", Message: syslogAccessLine},
+		},
+		"rfc 3164 without header": {
+			Message:  "<13>" + syslogAccessLine,
+			Expected: SyslogMessage{Message: syslogAccessLine},
+		},
+		"no priority": {
+			Message:       syslogAccessLine,
+			ExpectedError: errInvalidSyslog,
+		},
+		"invalid priority": {
+			Message:       "<999>1 - - - - - - " + syslogAccessLine,
+			ExpectedError: errInvalidSyslog,
+		},
+		"incomplete rfc 5424 header": {
+			Message:       "<190>1 2018-05-09T16:00:39Z web-1",
+			ExpectedError: errInvalidSyslog,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			actual, err := ParseSyslog([]byte(c.Message))
+			if !errors.Is(err, c.ExpectedError) {
+				t.Fatal("unexpected error", "expected", c.ExpectedError, "actual", err)
+			}
+			if c.Expected != actual {
+				t.Fatal("unexpected message", "expected", c.Expected, "actual", actual)
+			}
+		})
+	}
+}
+
+func TestScanFrames(t *testing.T) {
+	type testCase struct {
+		Stream         string
+		ExpectedFrames []string
+		ExpectedError  bool
+	}
+
+	cases := map[string]testCase{
+		"octet counting and new lines": {
+			Stream:         "11 octet\ncount" + "new line\r\n" + "\n" + "5 third",
+			ExpectedFrames: []string{"octet\ncount", "new line", "", "third"},
+		},
+		"line too long": {
+			Stream:         "first\n" + strings.Repeat("a", maxLineSize+maxFrameHeaderSize+1) + "\nlast\n",
+			ExpectedFrames: []string{"first"},
+			ExpectedError:  true,
+		},
+		"frame length too large": {
+			Stream:        fmt.Sprintf("%d message", maxLineSize+1),
+			ExpectedError: true,
+		},
+		"truncated frame": {
+			Stream:        "20 message",
+			ExpectedError: true,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			scanner := scanFrames(strings.NewReader(c.Stream))
+
+			var actual []string
+			for scanner.Scan() {
+				actual = append(actual, scanner.Text())
+			}
+			if (scanner.Err() != nil) != c.ExpectedError {
+				t.Fatal("unexpected error", "expected", c.ExpectedError, "actual", scanner.Err())
+			}
+			if !reflect.DeepEqual(c.ExpectedFrames, actual) {
+				t.Fatal("unexpected frames", "expected", c.ExpectedFrames, "actual", actual)
+			}
+		})
+	}
+}
+
+// freeAddress returns a local address available on the network
+func freeAddress(t *testing.T, network string) string {
+	if network == "udp" {
+		conn, err := net.ListenPacket(network, "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		return conn.LocalAddr().String()
+	}
+
+	listener, err := net.Listen(network, "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	return listener.Addr().String()
+}
+
+func TestSyslogSource_Receive(t *testing.T) {
+	udp := freeAddress(t, "udp")
+	tcp := freeAddress(t, "tcp")
+	source := NewSyslogSource([]string{"udp://" + udp, "tcp://" + tcp})
+	err := source.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer source.Stop()
+
+	expectedLabels := map[string]string{HostnameLabel: "web-1", AppNameLabel: "nginx"}
+	message := "<190>1 2018-05-09T16:00:39Z web-1 nginx - - - " + syslogAccessLine
+
+	conn, err := net.Dial("udp", udp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = conn.Write([]byte(message))
+	conn.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	line := readLine(t, source)
+	if line.Source != "udp://"+udp || line.Text != syslogAccessLine || !reflect.DeepEqual(expectedLabels, line.Labels) {
+		t.Fatal("unexpected udp line", "expected", syslogAccessLine, expectedLabels, "actual", line)
+	}
+
+	conn, err = net.Dial("tcp", tcp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	_, err = fmt.Fprintf(conn, "%d %s%s\n", len(message), message, message)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		line = readLine(t, source)
+		if line.Source != "tcp://"+tcp || line.Text != syslogAccessLine || !reflect.DeepEqual(expectedLabels, line.Labels) {
+			t.Fatal("unexpected tcp line", "expected", syslogAccessLine, expectedLabels, "actual", line)
+		}
+	}
+}
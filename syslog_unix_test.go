@@ -0,0 +1,33 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSyslogSource_RestartUnixgram(t *testing.T) {
+	dir, err := ioutil.TempDir("", "syslog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "monitor.sock")
+	for i := 0; i < 2; i++ {
+		source := NewSyslogSource([]string{"unixgram://" + path})
+		err := source.Start()
+		if err != nil {
+			t.Fatal("cannot start the source", "attempt", i, "err", err)
+		}
+		source.Stop()
+
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Fatal("unexpected socket file", "attempt", i, "err", err)
+		}
+	}
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// Default template of the payload posted to the webhook
+const defaultWebhookTemplate = `{"status":{{json .Status}},"fingerprint":{{json .Alert.Fingerprint}},"name":{{json .Alert.Name}},` +
+	`"subject":{{json .Alert.Subject}},"scope":{{json .Alert.Scope}},"key":{{json .Alert.Key}},"hits":{{.Alert.Hits}},` +
+	`"average_rate":{{.Alert.AverageRate}},"threshold":{{.Alert.Threshold}},"starts_at":{{json .Alert.StartsAt}},` +
+	`"triggered_at":{{json .Alert.TriggeredAt}}}`
+
+// Default values of the webhook delivery
+const (
+	defaultWebhookTimeout     = 5 * time.Second
+	defaultWebhookRetries     = 3
+	defaultWebhookBackoff     = 500 * time.Millisecond
+	defaultWebhookQueueSize   = 100
+	webhookPayloadContentType = "application/json"
+)
+
+// WebhookRetriesUnset is the number of retries not configured, replaced by the default one. 0 disables the retries
+const WebhookRetriesUnset = -1
+
+// ErrQueueFull is returned when an alert is dropped because the receiver is too slow
+var ErrQueueFull = errors.New("notification queue is full")
+
+var errNotifierClosed = errors.New("notifier is closed")
+
+// WebhookConfig describes how to deliver the alerts to a webhook,
+// zero values are replaced by the default ones
+type WebhookConfig struct {
+	URL        string        // Endpoint receiving the alerts
+	Template   string        // Go template of the JSON payload
+	Timeout    time.Duration // Timeout of a delivery attempt
+	MaxRetries int           // Number of retries after a failed delivery, WebhookRetriesUnset for the default
+	Backoff    time.Duration // Time to wait before the first retry, doubled after each retry
+	QueueSize  int           // Number of alerts waiting for delivery
+}
+
+// WebhookNotifier posts the alerts as JSON to an HTTP endpoint.
+// Alerts are queued and delivered by a background worker so a slow receiver never blocks the monitoring.
+type WebhookNotifier struct {
+	config   WebhookConfig
+	template *template.Template
+	client   *http.Client
+
+	queue chan Alert    // never closed, so a late Notify cannot send on a closed channel
+	done  chan struct{} // closed by Close to stop the worker and the retries
+	once  sync.Once
+	wg    sync.WaitGroup
+}
+
+// webhookPayload is the data available in the payload template
+type webhookPayload struct {
+	Status string
+	Alert  Alert
+}
+
+func NewWebhookNotifier(config WebhookConfig) (*WebhookNotifier, error) {
+	if len(config.URL) == 0 {
+		return nil, errors.New("webhook url is missing")
+	}
+	if len(config.Template) == 0 {
+		config.Template = defaultWebhookTemplate
+	}
+	if config.Timeout == 0 {
+		config.Timeout = defaultWebhookTimeout
+	}
+	if config.MaxRetries < 0 {
+		config.MaxRetries = defaultWebhookRetries
+	}
+	if config.Backoff == 0 {
+		config.Backoff = defaultWebhookBackoff
+	}
+	if config.QueueSize == 0 {
+		config.QueueSize = defaultWebhookQueueSize
+	}
+
+	tmpl, err := template.New("webhook").Funcs(template.FuncMap{"json": toJSON}).Parse(config.Template)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse webhook template: %w", err)
+	}
+
+	n := &WebhookNotifier{
+		config:   config,
+		template: tmpl,
+		client:   &http.Client{Timeout: config.Timeout},
+		queue:    make(chan Alert, config.QueueSize),
+		done:     make(chan struct{}),
+	}
+
+	n.wg.Add(1)
+	go n.run()
+
+	return n, nil
+}
+
+// Notify queues the alert, it returns ErrQueueFull when the alert cannot be queued
+func (n *WebhookNotifier) Notify(alert Alert) error {
+	select {
+	case <-n.done:
+		return fmt.Errorf("webhook: %w - alert %s dropped", errNotifierClosed, alert.ID())
+	default:
+	}
+
+	select {
+	case n.queue <- alert:
+		return nil
+	default:
+		return fmt.Errorf("webhook: %w - alert %s dropped", ErrQueueFull, alert.ID())
+	}
+}
+
+// Close stops the notifier once the queued alerts are delivered.
+// The failed deliveries are not retried anymore so an unavailable receiver does not delay the shutdown.
+// It can be called several times.
+func (n *WebhookNotifier) Close() error {
+	n.once.Do(func() {
+		close(n.done)
+	})
+	n.wg.Wait()
+	return nil
+}
+
+// run delivers the queued alerts, the alerts still queued are delivered once the notifier is closed
+func (n *WebhookNotifier) run() {
+	defer n.wg.Done()
+
+	for {
+		select {
+		case alert := <-n.queue:
+			n.handle(alert)
+		case <-n.done:
+			for {
+				select {
+				case alert := <-n.queue:
+					n.handle(alert)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// handle delivers the alert and logs the failed delivery
+func (n *WebhookNotifier) handle(alert Alert) {
+	err := n.deliver(alert)
+	if err != nil {
+		log.Println("cannot deliver alert to webhook", "alert", alert.ID(), "err", err)
+	}
+}
+
+// deliver posts the alert and retries with an exponential backoff on failure
+func (n *WebhookNotifier) deliver(alert Alert) error {
+	payload, err := n.payload(alert)
+	if err != nil {
+		return err
+	}
+
+	backoff := n.config.Backoff
+	for attempt := 0; ; attempt++ {
+		retry, err := n.post(payload)
+		if err == nil {
+			return nil
+		}
+
+		if !retry || attempt >= n.config.MaxRetries {
+			return fmt.Errorf("%w - attempts: %v", err, attempt+1)
+		}
+
+		select {
+		case <-n.done:
+			return fmt.Errorf("%w - attempts: %v - not retried, the notifier is closed", err, attempt+1)
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// payload renders the JSON payload of the alert
+func (n *WebhookNotifier) payload(alert Alert) ([]byte, error) {
+	var buffer bytes.Buffer
+	err := n.template.Execute(&buffer, webhookPayload{Status: alert.Status(), Alert: alert})
+	if err != nil {
+		return nil, fmt.Errorf("cannot render webhook payload: %w", err)
+	}
+
+	if !json.Valid(buffer.Bytes()) {
+		return nil, fmt.Errorf("webhook payload is not a valid json: %s", buffer.String())
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// post sends the payload and returns whether the delivery can be retried on failure
+func (n *WebhookNotifier) post(payload []byte) (bool, error) {
+	resp, err := n.client.Post(n.config.URL, webhookPayloadContentType, bytes.NewReader(payload))
+	if err != nil {
+		return true, err
+	}
+	defer func() {
+		err := resp.Body.Close()
+		if err != nil {
+			log.Println("failed to close webhook response", "err", err)
+		}
+	}()
+
+	if IsSuccess(resp.StatusCode) {
+		return true, nil
+	}
+
+	// the receiver is unavailable or overloaded, other errors will not change by retrying
+	retry := IsServerError(resp.StatusCode) || resp.StatusCode == http.StatusTooManyRequests
+	return retry, fmt.Errorf("unexpected webhook response status: %s", resp.Status)
+}
+
+// toJSON encodes a value in JSON to be embedded in a template
+func toJSON(value interface{}) (string, error) {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return string(raw), nil
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWebhookNotifier_Notify(t *testing.T) {
+	triggeredAt := time.Date(2006, 01, 02, 15, 04, 05, 000, time.UTC)
+	alert := Alert{
+		Name:        HighTrafficAlert,
+		Scope:       SectionScope,
+		Key:         "login",
+		State:       AlertFiring,
+		Hits:        6000,
+		AverageRate: 100,
+		Threshold:   50,
+		StartsAt:    triggeredAt,
+		TriggeredAt: triggeredAt,
+	}
+
+	type testCase struct {
+		Template        string
+		MaxRetries      int
+		Responses       []int
+		ExpectedPayload map[string]interface{}
+		ExpectedCalls   int
+	}
+
+	cases := map[string]testCase{
+		"default payload": {
+			Responses: []int{http.StatusOK},
+			ExpectedPayload: map[string]interface{}{
+				"status":       "firing",
+				"fingerprint":  alert.Fingerprint(),
+				"name":         "high_traffic",
+				"subject":      "section /login",
+				"scope":        "section",
+				"key":          "login",
+				"hits":         float64(6000),
+				"average_rate": float64(100),
+				"threshold":    float64(50),
+				"starts_at":    "2006-01-02T15:04:05Z",
+				"triggered_at": "2006-01-02T15:04:05Z",
+			},
+			ExpectedCalls: 1,
+		},
+		"templated payload": {
+			Template:  `{"text": {{json (printf "%s is %s" .Alert.Subject .Status)}}}`,
+			Responses: []int{http.StatusNoContent},
+			ExpectedPayload: map[string]interface{}{
+				"text": "section /login is firing",
+			},
+			ExpectedCalls: 1,
+		},
+		"retried after server errors": {
+			Template:   `{"status": {{json .Status}}}`,
+			MaxRetries: WebhookRetriesUnset,
+			Responses:  []int{http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusOK},
+			ExpectedPayload: map[string]interface{}{
+				"status": "firing",
+			},
+			ExpectedCalls: 3,
+		},
+		"retries disabled": {
+			Template:  `{"status": {{json .Status}}}`,
+			Responses: []int{http.StatusServiceUnavailable, http.StatusOK},
+			ExpectedPayload: map[string]interface{}{
+				"status": "firing",
+			},
+			ExpectedCalls: 1,
+		},
+		"not retried after client error": {
+			Template:  `{"status": {{json .Status}}}`,
+			Responses: []int{http.StatusBadRequest, http.StatusOK},
+			ExpectedPayload: map[string]interface{}{
+				"status": "firing",
+			},
+			ExpectedCalls: 1,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			var lock sync.Mutex
+			var calls int
+			var payload map[string]interface{}
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				lock.Lock()
+				defer lock.Unlock()
+
+				body, err := ioutil.ReadAll(r.Body)
+				if err != nil {
+					t.Error(err)
+				}
+				payload = nil
+				if err := json.Unmarshal(body, &payload); err != nil {
+					t.Error("invalid payload", "err", err, "body", string(body))
+				}
+
+				w.WriteHeader(c.Responses[calls])
+				calls++
+			}))
+			defer server.Close()
+
+			n, err := NewWebhookNotifier(WebhookConfig{URL: server.URL, Template: c.Template, MaxRetries: c.MaxRetries, Backoff: time.Millisecond})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = n.Notify(alert)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// waits for the delivery, the notifier does not retry once closed
+			deadline := time.Now().Add(5 * time.Second)
+			for {
+				lock.Lock()
+				delivered := calls >= c.ExpectedCalls
+				lock.Unlock()
+				if delivered || time.Now().After(deadline) {
+					break
+				}
+				time.Sleep(time.Millisecond)
+			}
+			// lets a retry not expected happen
+			time.Sleep(10 * time.Millisecond)
+			err = n.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if c.ExpectedCalls != calls {
+				t.Fatal("unexpected calls", "expected", c.ExpectedCalls, "actual", calls)
+			}
+
+			if !reflect.DeepEqual(c.ExpectedPayload, payload) {
+				t.Fatal("unexpected payload", "expected", c.ExpectedPayload, "actual", payload)
+			}
+		})
+	}
+}
+
+func TestWebhookNotifier_QueueFull(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// slow receiver
+		<-release
+	}))
+	defer server.Close()
+
+	n, err := NewWebhookNotifier(WebhookConfig{URL: server.URL, QueueSize: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var dropped int
+	for i := 0; i < 5; i++ {
+		err = n.Notify(Alert{Name: HighTrafficAlert, State: AlertFiring, Hits: int64(i)})
+		if errors.Is(err, ErrQueueFull) {
+			dropped++
+		}
+	}
+
+	// the first alert is being delivered and the second one waits in the queue
+	if dropped < 3 {
+		t.Fatal("unexpected dropped alerts", "expected at least", 3, "actual", dropped)
+	}
+
+	close(release)
+	err = n.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewWebhookNotifier_InvalidTemplate(t *testing.T) {
+	_, err := NewWebhookNotifier(WebhookConfig{URL: "http://localhost", Template: `{"status": {{json .Status}`})
+	if err == nil {
+		t.Fatal("expected error not occurred")
+	}
+}
+
+func TestWebhookNotifier_CloseDuringBackoff(t *testing.T) {
+	called := make(chan struct{}, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called <- struct{}{}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	n, err := NewWebhookNotifier(WebhookConfig{URL: server.URL, MaxRetries: WebhookRetriesUnset, Backoff: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = n.Notify(Alert{Name: HighTrafficAlert, State: AlertFiring})
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-called
+
+	closed := make(chan error)
+	go func() {
+		closed <- n.Close()
+	}()
+	select {
+	case err := <-closed:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("notifier not closed during the backoff")
+	}
+}
+
+func TestWebhookNotifier_NotifyAfterClose(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	n, err := NewWebhookNotifier(WebhookConfig{URL: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = n.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the notifier can be closed again and the alerts are dropped
+	err = n.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = n.Notify(Alert{Name: HighTrafficAlert, State: AlertFiring})
+	if !errors.Is(err, errNotifierClosed) {
+		t.Fatal("unexpected error", "expected", errNotifierClosed, "actual", err)
+	}
+}